@@ -0,0 +1,63 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssc32u
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Actuator drives a Board through the channel/pulse interface used by the spider package's JointActuator.
+// Pulses are buffered on the board's servos and sent together on Commit, as a single move lasting MoveTime.
+type Actuator struct {
+	Board    *Board
+	MoveTime time.Duration
+}
+
+func NewActuator(b *Board, moveTime time.Duration) *Actuator {
+	return &Actuator{
+		Board:    b,
+		MoveTime: moveTime,
+	}
+}
+
+// SetPulse sets the pulse width of a channel, adding a servo to the board if necessary.
+// A value of 0 turns off the servo.
+func (a *Actuator) SetPulse(channel byte, micros uint16) error {
+	if channel > 31 {
+		return fmt.Errorf("invalid channel: %d", channel)
+	}
+	name := fmt.Sprintf("ch%d", channel)
+	servo := a.Board.Servo(name)
+	if servo == nil {
+		servo = a.Board.AddServo(uint(channel), name)
+	}
+	if micros == 0 {
+		// P0 stops the pulses, so bypass the clamping in SetPosition.
+		servo.position = 0
+		servo.isModified = true
+		return nil
+	}
+	servo.SetPosition(uint(micros))
+	return nil
+}
+
+func (a *Actuator) Commit() error {
+	if a.Board.port == nil {
+		return errors.New("board is not connected")
+	}
+	return a.Board.Commit(uint(a.MoveTime / time.Millisecond))
+}
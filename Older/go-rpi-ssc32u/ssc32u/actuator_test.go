@@ -0,0 +1,53 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssc32u
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActuatorSetPulse(t *testing.T) {
+	var tests = []struct {
+		in   uint16
+		want string
+	}{
+		{1500, "#7 P1500 "},
+		{9999, "#7 P2500 "},
+		{0, "#7 P0 "},
+	}
+
+	for _, tt := range tests {
+		b := New()
+		a := NewActuator(&b, 100*time.Millisecond)
+		if err := a.SetPulse(7, tt.in); err != nil {
+			t.Errorf("SetPulse(7, %d) returned %v", tt.in, err)
+		}
+		if got := b.Servo("ch7").commandString(); got != tt.want {
+			t.Errorf("SetPulse(7, %d) command = %s; want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestActuatorErrors(t *testing.T) {
+	b := New()
+	a := NewActuator(&b, 100*time.Millisecond)
+	if err := a.SetPulse(32, 1500); err == nil {
+		t.Error("SetPulse(32, 1500) succeeded; want an error")
+	}
+	if err := a.Commit(); err == nil {
+		t.Error("Commit() on an unconnected board succeeded; want an error")
+	}
+}
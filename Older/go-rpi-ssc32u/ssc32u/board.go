@@ -56,15 +56,19 @@ func (b *Board) Close() {
 	}
 }
 
-func (b *Board) Commit(micros uint) {
-	if b.port != nil {
-		_, err := b.port.Write([]byte(b.commandString(micros)))
-		if err == nil {
-			for _, servo := range b.servos {
-				servo.isModified = false
-			}
-		}
+// Commit sends the modified servo positions to the board, as a single move lasting the given number of milliseconds.
+// It returns the error from writing to the port, if any, in which case the positions are sent again on the next Commit.
+func (b *Board) Commit(millis uint) error {
+	if b.port == nil {
+		return nil
+	}
+	if _, err := b.port.Write([]byte(b.commandString(millis))); err != nil {
+		return err
+	}
+	for _, servo := range b.servos {
+		servo.isModified = false
 	}
+	return nil
 }
 
 func (b *Board) AddServo(id uint, name string) *Servo {
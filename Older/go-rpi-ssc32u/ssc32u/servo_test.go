@@ -4,7 +4,7 @@ import "testing"
 
 func TestSetAngleDegrees(t *testing.T) {
 	var tests = []struct {
-		in   float32
+		in   float64
 		want uint
 	}{
 		{-90, 500},
@@ -43,12 +43,30 @@ func main() {
 	// inbuf := make([]byte, 64)
 	// inbufIdx := 0
 	// uart := machine.UART0
+	spdr.PowerOn(spider.TibiaLength, 4*time.Second)
 	theta := 0.0
-	for {
-		spdr.SendCommandsToServos()
-		time.Sleep(10 * time.Millisecond)
-		theta += math.Pi / 50
-		spdr.SetAll(spider.Point3D{math.Sin(theta) * 20, math.Cos(theta) * 20, math.Sin(theta/2) * 5})
+	// The demo fades in over a second, so that the feet don't jump from the neutral pose that PowerOn finishes in.
+	amplitude := 0.0
+	// Frames which were sent with some joints clamped to their servo's range are only reported in the frame status.
+	// A frame which didn't get through is printed, but only when the error changes, so as not to flood the console.
+	lastErr := ""
+	loop := spider.NewLoop(spider.SystemClock, 10*time.Millisecond)
+	loop.Run(func(dt time.Duration) bool {
+		err := spdr.SendCommandsToServos()
+		switch {
+		case spdr.FrameStatus().Delivered:
+			lastErr = ""
+		case err.Error() != lastErr:
+			fmt.Println(err)
+			lastErr = err.Error()
+		}
+		if spdr.Moving() {
+			spdr.Update(dt)
+			return true
+		}
+		theta += math.Pi * dt.Seconds()
+		amplitude = math.Min(1, amplitude+dt.Seconds())
+		spdr.SetAll(spider.Point3D{math.Sin(theta) * 20, math.Cos(theta) * 20, math.Sin(theta/2) * 5}.Scale(amplitude))
 		// if uart.Buffered() > 0 {
 		// 	data, _ := uart.ReadByte()
 		// 	// Echo what the user types.
@@ -91,5 +109,6 @@ func main() {
 		// 		inbufIdx++
 		// 	}
 		// }
-	}
+		return true
+	})
 }
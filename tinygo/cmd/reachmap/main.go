@@ -0,0 +1,162 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Reachmap sweeps the workspace of each leg through the IK and servo limits, and writes out the reachable toe points.
+// It runs on the host, e.g.
+//
+//	go run ./cmd/reachmap -format ply -o workspace.ply
+//	go run ./cmd/reachmap -format strides
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/timboldt/spiderbot/pkg/spider"
+)
+
+var (
+	format = flag.String("format", "csv", "output format: csv (one row per reachable voxel), heightmap (lowest and highest reachable point of each column), ply (point cloud), or strides (max stride for each body height)")
+	step   = flag.Float64("step", 5, "grid spacing")
+	out    = flag.String("o", "", "output file (default stdout)")
+)
+
+var legNames = []string{"FR", "FL", "BR", "BL"}
+
+// Point colours for PLY output, by leg.
+var legColors = [][3]uint8{{230, 60, 60}, {60, 160, 60}, {60, 90, 230}, {220, 180, 40}}
+
+func main() {
+	flag.Parse()
+	if !(*step > 0) {
+		fmt.Fprintf(os.Stderr, "-step must be positive, not %v\n", *step)
+		flag.Usage()
+		os.Exit(2)
+	}
+	var f *os.File
+	w := os.Stdout
+	if *out != "" {
+		var err error
+		f, err = os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+
+	s := spider.New(spider.NewRecorder(), spider.DefaultConfig())
+	if *format == "strides" {
+		writeStrides(bw, s)
+		finish(bw, f)
+		return
+	}
+
+	// Toe points are converted to the body frame, so that all four legs can be shown together.
+	var pts [4][]spider.Point3D
+	for leg := spider.LegPosition(0); leg < 4; leg++ {
+		min, max := s.WorkspaceBounds(leg)
+		zero := s.ZeroToePoint(leg)
+		for _, pt := range s.ReachableToePoints(leg, min, max, *step) {
+			pts[leg] = append(pts[leg], pt.Add(zero))
+		}
+	}
+	switch *format {
+	case "csv":
+		writeCSV(bw, pts)
+	case "heightmap":
+		writeHeightmap(bw, pts)
+	case "ply":
+		writePLY(bw, pts)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format: %q\n", *format)
+		os.Exit(2)
+	}
+	finish(bw, f)
+}
+
+// finish flushes the output, and closes the output file if there is one. A write error only shows up here, so it
+// exits with an error if either fails.
+func finish(bw *bufio.Writer, f *os.File) {
+	err := bw.Flush()
+	if f != nil {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func writeCSV(w io.Writer, pts [4][]spider.Point3D) {
+	fmt.Fprintln(w, "leg,x,y,z")
+	for leg := range pts {
+		for _, pt := range pts[leg] {
+			fmt.Fprintf(w, "%s,%.1f,%.1f,%.1f\n", legNames[leg], pt.X, pt.Y, pt.Z)
+		}
+	}
+}
+
+func writeHeightmap(w io.Writer, pts [4][]spider.Point3D) {
+	type column struct{ x, y float64 }
+	fmt.Fprintln(w, "leg,x,y,zmin,zmax")
+	for leg := range pts {
+		var order []column
+		low := map[column]float64{}
+		high := map[column]float64{}
+		for _, pt := range pts[leg] {
+			c := column{pt.X, pt.Y}
+			if _, ok := low[c]; !ok {
+				order = append(order, c)
+				low[c] = math.Inf(1)
+				high[c] = math.Inf(-1)
+			}
+			low[c] = math.Min(low[c], pt.Z)
+			high[c] = math.Max(high[c], pt.Z)
+		}
+		for _, c := range order {
+			fmt.Fprintf(w, "%s,%.1f,%.1f,%.1f,%.1f\n", legNames[leg], c.x, c.y, low[c], high[c])
+		}
+	}
+}
+
+func writePLY(w io.Writer, pts [4][]spider.Point3D) {
+	n := 0
+	for leg := range pts {
+		n += len(pts[leg])
+	}
+	fmt.Fprintf(w, "ply\nformat ascii 1.0\nelement vertex %d\n", n)
+	fmt.Fprintln(w, "property float x\nproperty float y\nproperty float z")
+	fmt.Fprintln(w, "property uchar red\nproperty uchar green\nproperty uchar blue\nend_header")
+	for leg := range pts {
+		c := legColors[leg]
+		for _, pt := range pts[leg] {
+			fmt.Fprintf(w, "%.1f %.1f %.1f %d %d %d\n", pt.X, pt.Y, pt.Z, c[0], c[1], c[2])
+		}
+	}
+}
+
+func writeStrides(w io.Writer, s *spider.Spider) {
+	fmt.Fprintln(w, "body_height,max_stride")
+	for h := *step; h <= spider.FemurLength+spider.TibiaLength; h += *step {
+		fmt.Fprintf(w, "%.1f,%.1f\n", h, s.MaxStride(h))
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"github.com/timboldt/spiderbot/pkg/pca9685"
+)
+
+// JointActuator drives the servo channels.
+// Pulses set with SetPulse are not guaranteed to take effect until Commit is called.
+// A pulse of 0 turns off the channel.
+type JointActuator interface {
+	SetPulse(channel byte, micros uint16) error
+	Commit() error
+}
+
+// NewPCA9685Actuator returns an actuator that drives a PCA9685 board.
+// The board applies each pulse as soon as it is set, so Commit does nothing.
+func NewPCA9685Actuator(dev pca9685.Device) JointActuator {
+	return &pca9685Actuator{dev: dev}
+}
+
+type pca9685Actuator struct {
+	dev pca9685.Device
+}
+
+func (a *pca9685Actuator) SetPulse(channel byte, micros uint16) error {
+	return a.dev.SetPin(channel, micros)
+}
+
+func (a *pca9685Actuator) Commit() error {
+	return nil
+}
+
+// Recorder is an in-memory actuator that keeps every committed frame, for tests and simulation.
+type Recorder struct {
+	// Frames holds one entry per Commit, oldest first.
+	// Each frame holds the latest pulse for every channel that has been set so far.
+	Frames  []map[byte]uint16
+	pending map[byte]uint16
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{pending: make(map[byte]uint16)}
+}
+
+func (r *Recorder) SetPulse(channel byte, micros uint16) error {
+	r.pending[channel] = micros
+	return nil
+}
+
+func (r *Recorder) Commit() error {
+	frame := make(map[byte]uint16, len(r.pending))
+	for ch, micros := range r.pending {
+		frame[ch] = micros
+	}
+	r.Frames = append(r.Frames, frame)
+	return nil
+}
+
+// Pulse returns the most recently committed pulse for a channel, or 0 if it has never been committed.
+func (r *Recorder) Pulse(channel byte) uint16 {
+	if len(r.Frames) == 0 {
+		return 0
+	}
+	return r.Frames[len(r.Frames)-1][channel]
+}
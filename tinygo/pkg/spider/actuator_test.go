@@ -0,0 +1,61 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	r.SetPulse(3, 1500)
+	if got := r.Pulse(3); got != 0 {
+		t.Errorf("Pulse(3) before Commit() = %d, want 0", got)
+	}
+	r.Commit()
+	r.SetPulse(4, 1200)
+	r.Commit()
+	if len(r.Frames) != 2 {
+		t.Fatalf("len(Frames) = %d, want 2", len(r.Frames))
+	}
+	if _, ok := r.Frames[0][4]; ok {
+		t.Error("first frame contains a pulse that was set after it was committed")
+	}
+	if got := r.Frames[1][3]; got != 1500 {
+		t.Errorf("second frame channel 3 = %d, want 1500 to carry over", got)
+	}
+	if got := r.Pulse(4); got != 1200 {
+		t.Errorf("Pulse(4) = %d, want 1200", got)
+	}
+}
+
+func TestSendCommandsToServos(t *testing.T) {
+	r := NewRecorder()
+	s := New(r, DefaultConfig())
+	s.SetAll(Point3D{X: 5, Y: -5, Z: -10})
+	s.SendCommandsToServos()
+	if len(r.Frames) != 1 {
+		t.Fatalf("SendCommandsToServos() committed %d frames, want 1", len(r.Frames))
+	}
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		bc, cf, ft := s.legs[leg].JointAngles()
+		for joint, rad := range [3]float64{bc, cf, ft} {
+			servo := s.servos[servoId(leg, Joint(joint))]
+			if got, want := r.Pulse(servo.Pin()), servo.RadiansToMicros(rad); got != want {
+				t.Errorf("leg %d joint %d pulse = %d, want %d", leg, joint, got, want)
+			}
+		}
+	}
+}
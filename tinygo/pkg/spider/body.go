@@ -0,0 +1,84 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+)
+
+// BodyPose is the position and orientation of the body relative to its neutral stance, in the world frame.
+// Angles are in radians, and follow the right-hand rule:
+// roll is about the Y axis (positive lowers the right side),
+// pitch is about the X axis (positive raises the front),
+// and yaw is about the Z axis (positive turns left).
+type BodyPose struct {
+	Translation      Point3D
+	Roll, Pitch, Yaw float64
+}
+
+// rotation returns the rotation matrix for the pose, applying roll, then pitch, then yaw.
+func (p BodyPose) rotation() [3][3]float64 {
+	cr, sr := math.Cos(p.Roll), math.Sin(p.Roll)
+	cp, sp := math.Cos(p.Pitch), math.Sin(p.Pitch)
+	cy, sy := math.Cos(p.Yaw), math.Sin(p.Yaw)
+	// Rz(yaw) * Rx(pitch) * Ry(roll)
+	return [3][3]float64{
+		{cy*cr - sy*sp*sr, -sy * cp, cy*sr + sy*sp*cr},
+		{sy*cr + cy*sp*sr, cy * cp, sy*sr - cy*sp*cr},
+		{-cp * sr, sp, cp * cr},
+	}
+}
+
+// bodyTransform is a BodyPose with its rotation matrix worked out, for converting many points at once.
+type bodyTransform struct {
+	r [3][3]float64
+	t Point3D
+}
+
+func (p BodyPose) transform() bodyTransform {
+	return bodyTransform{r: p.rotation(), t: p.Translation}
+}
+
+// toWorld converts a point in the body frame to the world frame.
+func (p BodyPose) toWorld(pt Point3D) Point3D {
+	b := p.transform()
+	return b.toWorld(pt)
+}
+
+// toBody converts a point in the world frame to the body frame.
+func (p BodyPose) toBody(pt Point3D) Point3D {
+	b := p.transform()
+	return b.toBody(pt)
+}
+
+func (b *bodyTransform) toWorld(pt Point3D) Point3D {
+	r := &b.r
+	return Point3D{
+		X: r[0][0]*pt.X + r[0][1]*pt.Y + r[0][2]*pt.Z,
+		Y: r[1][0]*pt.X + r[1][1]*pt.Y + r[1][2]*pt.Z,
+		Z: r[2][0]*pt.X + r[2][1]*pt.Y + r[2][2]*pt.Z,
+	}.Add(b.t)
+}
+
+func (b *bodyTransform) toBody(pt Point3D) Point3D {
+	r := &b.r
+	pt = pt.Sub(b.t)
+	// The inverse of a rotation matrix is its transpose.
+	return Point3D{
+		X: r[0][0]*pt.X + r[1][0]*pt.Y + r[2][0]*pt.Z,
+		Y: r[0][1]*pt.X + r[1][1]*pt.Y + r[2][1]*pt.Z,
+		Z: r[0][2]*pt.X + r[1][2]*pt.Y + r[2][2]*pt.Z,
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+)
+
+func newTestSpider() *Spider {
+	return New(NewRecorder(), DefaultConfig())
+}
+
+func approxEqualWithin(a, b Point3D, epsilon float64) bool {
+	return math.Abs(a.X-b.X) < epsilon && math.Abs(a.Y-b.Y) < epsilon && math.Abs(a.Z-b.Z) < epsilon
+}
+
+func approxEqual(a, b Point3D) bool {
+	return approxEqualWithin(a, b, 1e-9)
+}
+
+func TestBodyPoseRoundTrip(t *testing.T) {
+	p := BodyPose{Translation: Point3D{X: 3, Y: -4, Z: 5}, Roll: 0.1, Pitch: -0.2, Yaw: 0.3}
+	pt := Point3D{X: 10, Y: 20, Z: -30}
+	if got := p.toBody(p.toWorld(pt)); !approxEqual(got, pt) {
+		t.Errorf("toBody(toWorld(%v)) = %v", pt, got)
+	}
+}
+
+func TestBodyPoseRotationDirections(t *testing.T) {
+	tests := []struct {
+		pose BodyPose
+		pt   Point3D
+		want Point3D
+	}{
+		// Yaw left: a point ahead of the body swings to the left.
+		{BodyPose{Yaw: math.Pi / 2}, Point3D{Y: 1}, Point3D{X: -1}},
+		// Pitch up: a point ahead of the body rises.
+		{BodyPose{Pitch: math.Pi / 2}, Point3D{Y: 1}, Point3D{Z: 1}},
+		// Roll: a point on the left of the body rises.
+		{BodyPose{Roll: math.Pi / 2}, Point3D{X: -1}, Point3D{Z: 1}},
+	}
+	for _, tt := range tests {
+		if got := tt.pose.toWorld(tt.pt); !approxEqual(got, tt.want) {
+			t.Errorf("%+v.toWorld(%v) = %v, want %v", tt.pose, tt.pt, got, tt.want)
+		}
+	}
+}
+
+func TestSetBodyPoseKeepsFeetPlanted(t *testing.T) {
+	s := newTestSpider()
+	s.SetAll(Point3D{X: 5, Y: -5, Z: -10})
+	s.SetBodyPose(Point3D{X: 4, Y: 6, Z: -8}, 0.1, -0.15, 0.2)
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		l := s.legs[leg]
+		got := s.body.toWorld(l.toePt.Add(l.origin)).Sub(l.origin)
+		if !approxEqual(got, s.ToePoint(leg)) {
+			t.Errorf("leg %v toe is at %v in the world frame, want %v", leg, got, s.ToePoint(leg))
+		}
+	}
+}
+
+func TestSetBodyPoseTranslation(t *testing.T) {
+	s := newTestSpider()
+	s.SetBodyPose(Point3D{X: 1, Y: 2, Z: 3}, 0, 0, 0)
+	// Moving the body one way moves the toes the other way relative to the hips.
+	want := Point3D{X: -1, Y: -2, Z: -3}
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if got := s.legs[leg].toePt; !approxEqual(got, want) {
+			t.Errorf("leg %v toe is at %v, want %v", leg, got, want)
+		}
+	}
+}
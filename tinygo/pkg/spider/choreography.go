@@ -0,0 +1,249 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keyframe is a pose to be reached at a given time.
+type Keyframe struct {
+	Time time.Duration
+	// Easing used for the move from the previous keyframe.
+	Easing Easing
+	Pose   Pose
+}
+
+// Choreography is a sequence of keyframes, in time order.
+type Choreography struct {
+	Keyframes []Keyframe
+}
+
+var easingNames = map[string]Easing{
+	"linear": Linear,
+	"in":     EaseIn,
+	"out":    EaseOut,
+	"inout":  EaseInOut,
+}
+
+var legNames = map[string]LegPosition{
+	"FR": FrontRight,
+	"FL": FrontLeft,
+	"BR": BackRight,
+	"BL": BackLeft,
+}
+
+// ParseChoreography reads a choreography in a simple line-based text format:
+//
+//	# Anything after a '#' is a comment.
+//	at 0.5 inout           # Start a keyframe at 0.5s, eased in and out. The easing is optional, and defaults to linear.
+//	FR 10 20 -5            # Toe point for one leg (FR, FL, BR, or BL)...
+//	all 0 0 0              # ... or for all of them.
+//	body 0 0 10 0 5 0      # Body translation, and optionally roll, pitch, and yaw in degrees.
+//
+// Anything not mentioned in a keyframe stays as it was in the previous keyframe; the first keyframe starts from the
+// neutral pose. Easings are linear, in, out, and inout.
+func ParseChoreography(r io.Reader) (*Choreography, error) {
+	c := &Choreography{}
+	var kf *Keyframe
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("choreography line %d: %s", lineNum, fmt.Sprintf(format, args...))
+		}
+
+		if fields[0] == "at" {
+			if len(fields) < 2 || len(fields) > 3 {
+				return nil, errorf("want 'at <seconds> [easing]'")
+			}
+			secs, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil || secs < 0 {
+				return nil, errorf("bad time %q", fields[1])
+			}
+			next := Keyframe{Time: time.Duration(secs * float64(time.Second)), Easing: Linear}
+			if kf != nil {
+				if next.Time < kf.Time {
+					return nil, errorf("time %v is before the previous keyframe", next.Time)
+				}
+				next.Pose = kf.Pose
+			}
+			if len(fields) == 3 {
+				easing, ok := easingNames[fields[2]]
+				if !ok {
+					return nil, errorf("unknown easing %q", fields[2])
+				}
+				next.Easing = easing
+			}
+			c.Keyframes = append(c.Keyframes, next)
+			kf = &c.Keyframes[len(c.Keyframes)-1]
+			continue
+		}
+
+		if kf == nil {
+			return nil, errorf("%q before the first 'at'", fields[0])
+		}
+		vals := make([]float64, len(fields)-1)
+		for i, f := range fields[1:] {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, errorf("bad number %q", f)
+			}
+			vals[i] = v
+		}
+		switch fields[0] {
+		case "all":
+			if len(vals) != 3 {
+				return nil, errorf("want 'all <x> <y> <z>'")
+			}
+			for leg := range kf.Pose.Feet {
+				kf.Pose.Feet[leg] = Point3D{X: vals[0], Y: vals[1], Z: vals[2]}
+			}
+		case "body":
+			if len(vals) != 3 && len(vals) != 6 {
+				return nil, errorf("want 'body <x> <y> <z> [<roll> <pitch> <yaw>]'")
+			}
+			kf.Pose.Body.Translation = Point3D{X: vals[0], Y: vals[1], Z: vals[2]}
+			if len(vals) == 6 {
+				kf.Pose.Body.Roll = vals[3] * math.Pi / 180
+				kf.Pose.Body.Pitch = vals[4] * math.Pi / 180
+				kf.Pose.Body.Yaw = vals[5] * math.Pi / 180
+			}
+		default:
+			leg, ok := legNames[fields[0]]
+			if !ok {
+				return nil, errorf("unknown target %q", fields[0])
+			}
+			if len(vals) != 3 {
+				return nil, errorf("want '%s <x> <y> <z>'", fields[0])
+			}
+			kf.Pose.Feet[leg] = Point3D{X: vals[0], Y: vals[1], Z: vals[2]}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Player drives a spider through a choreography.
+type Player struct {
+	s       *Spider
+	c       *Choreography
+	speed   float64
+	loop    bool
+	next    int
+	playing bool
+	// Time left until the keyframe being moved to is reached.
+	left time.Duration
+}
+
+// NewPlayer checks that every keyframe is reachable, and returns a player ready to start.
+func NewPlayer(s *Spider, c *Choreography) (*Player, error) {
+	for i, kf := range c.Keyframes {
+		if err := s.CheckReachable(kf.Pose); err != nil {
+			return nil, fmt.Errorf("keyframe %d at %v: %v", i, kf.Time, err)
+		}
+	}
+	return &Player{
+		s:       s,
+		c:       c,
+		speed:   1,
+		playing: len(c.Keyframes) > 0,
+	}, nil
+}
+
+// SetSpeed scales the playback speed; 2 plays twice as fast. The speed must be positive.
+// It takes effect from the next keyframe.
+func (p *Player) SetSpeed(speed float64) error {
+	if !(speed > 0) {
+		return fmt.Errorf("spider: playback speed must be positive, not %v", speed)
+	}
+	p.speed = speed
+	return nil
+}
+
+// SetLoop makes the choreography start again from the first keyframe when it finishes.
+func (p *Player) SetLoop(loop bool) {
+	p.loop = loop
+}
+
+// Playing reports whether there is any of the choreography left to play.
+func (p *Player) Playing() bool {
+	return p.playing
+}
+
+// Stop stops playback, leaving the spider where it is.
+func (p *Player) Stop() {
+	p.playing = false
+	p.s.CancelMove()
+}
+
+// Update advances playback, and the spider, by the given amount of time.
+// If a keyframe is reached part way through, the rest of the time goes towards the next one.
+func (p *Player) Update(dt time.Duration) {
+	p.startKeyframe()
+	// A looping choreography with no length would never use up the time, so a tick can cover each keyframe at most once.
+	for i := 0; i < len(p.c.Keyframes) && p.s.Moving() && dt > p.left; i++ {
+		p.s.Update(p.left)
+		dt -= p.left
+		p.left = 0
+		p.startKeyframe()
+	}
+	p.s.Update(dt)
+	p.left -= dt
+	if p.left < 0 {
+		p.left = 0
+	}
+	p.startKeyframe()
+}
+
+// startKeyframe starts the move to the next keyframe once the spider has finished the last one, or stops playback
+// at the end of the choreography.
+func (p *Player) startKeyframe() {
+	if !p.playing || p.s.Moving() {
+		return
+	}
+	if p.next == len(p.c.Keyframes) {
+		p.next = 0
+		p.playing = p.loop
+		if !p.playing {
+			return
+		}
+	}
+	kf := p.c.Keyframes[p.next]
+	// The first keyframe's time is how long to take getting there from wherever the spider is.
+	d := kf.Time
+	if p.next > 0 {
+		d -= p.c.Keyframes[p.next-1].Time
+	}
+	d = time.Duration(float64(d) / p.speed)
+	p.s.MoveTo(kf.Pose, d, kf.Easing)
+	p.left = d
+	p.next++
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDance = `
+# Crouch, then lean forward and lift a foot.
+at 1 inout
+all 0 0 10
+
+at 1.5
+body 5 0 0  0 10 0   # Pitch up 10 degrees.
+FR 0 0 20
+`
+
+func TestParseChoreography(t *testing.T) {
+	c, err := ParseChoreography(strings.NewReader(testDance))
+	if err != nil {
+		t.Fatalf("ParseChoreography() returned %v", err)
+	}
+	if len(c.Keyframes) != 2 {
+		t.Fatalf("ParseChoreography() returned %d keyframes, want 2", len(c.Keyframes))
+	}
+	kf := c.Keyframes[1]
+	if kf.Time != 1500*time.Millisecond {
+		t.Errorf("second keyframe is at %v, want 1.5s", kf.Time)
+	}
+	if got, want := kf.Pose.Feet[FrontRight], (Point3D{Z: 20}); got != want {
+		t.Errorf("second keyframe FR = %v, want %v", got, want)
+	}
+	// Unmentioned legs carry over from the previous keyframe.
+	if got, want := kf.Pose.Feet[BackLeft], (Point3D{Z: 10}); got != want {
+		t.Errorf("second keyframe BL = %v, want %v", got, want)
+	}
+	if got, want := kf.Pose.Body.Pitch, 10*math.Pi/180; math.Abs(got-want) > 1e-9 {
+		t.Errorf("second keyframe pitch = %v, want %v", got, want)
+	}
+	if kf.Easing(0.25) != 0.25 || c.Keyframes[0].Easing(0.25) == 0.25 {
+		t.Error("keyframes have the wrong easing")
+	}
+}
+
+func TestParseChoreographyErrors(t *testing.T) {
+	tests := []string{
+		"FR 0 0 0",
+		"at",
+		"at -1",
+		"at 1 bouncy",
+		"at 2\nat 1",
+		"at 1\nFR 0 0",
+		"at 1\nXX 0 0 0",
+		"at 1\nbody 0 0 0 0",
+		"at 1\nall a b c",
+	}
+	for _, tt := range tests {
+		if _, err := ParseChoreography(strings.NewReader(tt)); err == nil {
+			t.Errorf("ParseChoreography(%q) succeeded, want an error", tt)
+		}
+	}
+}
+
+func TestPlayerRejectsUnreachableKeyframes(t *testing.T) {
+	c, err := ParseChoreography(strings.NewReader("at 1\nat 2\nFL 0 0 500\n"))
+	if err != nil {
+		t.Fatalf("ParseChoreography() returned %v", err)
+	}
+	if _, err := NewPlayer(newTestSpider(), c); err == nil {
+		t.Error("NewPlayer() accepted an unreachable keyframe")
+	}
+}
+
+func TestPlayerRejectsBadSpeeds(t *testing.T) {
+	p, err := NewPlayer(newTestSpider(), &Choreography{})
+	if err != nil {
+		t.Fatalf("NewPlayer() returned %v", err)
+	}
+	for _, speed := range []float64{0, -1, math.NaN()} {
+		if err := p.SetSpeed(speed); err == nil {
+			t.Errorf("SetSpeed(%v) succeeded, want an error", speed)
+		}
+	}
+	if p.speed != 1 {
+		t.Errorf("speed after rejected SetSpeed calls = %v, want 1", p.speed)
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	c, err := ParseChoreography(strings.NewReader(testDance))
+	if err != nil {
+		t.Fatalf("ParseChoreography() returned %v", err)
+	}
+	tests := []struct {
+		speed float64
+		want  time.Duration
+	}{
+		{1, 1500 * time.Millisecond},
+		{2, 750 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		s := newTestSpider()
+		p, err := NewPlayer(s, c)
+		if err != nil {
+			t.Fatalf("NewPlayer() returned %v", err)
+		}
+		if err := p.SetSpeed(tt.speed); err != nil {
+			t.Fatalf("SetSpeed(%v) returned %v", tt.speed, err)
+		}
+		elapsed := time.Duration(0)
+		for p.Playing() && elapsed < 10*time.Second {
+			p.Update(10 * time.Millisecond)
+			elapsed += 10 * time.Millisecond
+		}
+		if elapsed != tt.want {
+			t.Errorf("at speed %v, playback took %v, want %v", tt.speed, elapsed, tt.want)
+		}
+		if got, want := s.Pose(), c.Keyframes[1].Pose; !posesEqual(got, want) {
+			t.Errorf("at speed %v, Pose() after playback = %+v, want %+v", tt.speed, got, want)
+		}
+	}
+}
+
+func TestPlayerLoops(t *testing.T) {
+	c, err := ParseChoreography(strings.NewReader(testDance))
+	if err != nil {
+		t.Fatalf("ParseChoreography() returned %v", err)
+	}
+	s := newTestSpider()
+	p, err := NewPlayer(s, c)
+	if err != nil {
+		t.Fatalf("NewPlayer() returned %v", err)
+	}
+	p.SetLoop(true)
+	for i := 0; i < 1000; i++ {
+		p.Update(10 * time.Millisecond)
+	}
+	if !p.Playing() {
+		t.Error("Playing() = false while looping")
+	}
+	p.Stop()
+	if p.Playing() || s.Moving() {
+		t.Error("still playing after Stop()")
+	}
+}
+
+func TestPlayerCarriesTimeAcrossKeyframes(t *testing.T) {
+	c := &Choreography{Keyframes: []Keyframe{
+		{Time: 0, Easing: Linear},
+		{Time: 25 * time.Millisecond, Easing: Linear, Pose: Pose{Body: BodyPose{Translation: Point3D{X: 10}}}},
+		{Time: 50 * time.Millisecond, Easing: Linear},
+	}}
+	s := newTestSpider()
+	p, err := NewPlayer(s, c)
+	if err != nil {
+		t.Fatalf("NewPlayer() returned %v", err)
+	}
+	// The third tick reaches the second keyframe half way through, and spends the rest on the way to the third.
+	for i := 0; i < 4; i++ {
+		p.Update(10 * time.Millisecond)
+	}
+	if got := s.BodyPose().Translation.X; math.Abs(got-4) > 1e-9 {
+		t.Errorf("after 40ms, the body is at X = %v, want 4", got)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrCollision is returned by SendCommandsToServos when collision checking rejects a frame.
+var ErrCollision = errors.New("spider: frame would cause a collision")
+
+// Collision model: each leg segment is a capsule of this radius around the line between its joints,
+// and the body is a box of the given half height around the plane of the hips.
+const (
+	SegmentRadius  = 6.0
+	BodyHalfHeight = 10.0
+)
+
+// Segment identifies a leg segment.
+type Segment uint8
+
+const (
+	Coxa Segment = iota
+	Femur
+	Tibia
+)
+
+func (seg Segment) String() string {
+	switch seg {
+	case Coxa:
+		return "coxa"
+	case Femur:
+		return "femur"
+	case Tibia:
+		return "tibia"
+	}
+	return fmt.Sprintf("Segment(%d)", int(seg))
+}
+
+// Collision is a leg segment touching either the body or a segment of another leg.
+type Collision struct {
+	Leg     LegPosition
+	Segment Segment
+	// If Unknown is set, the segment's position couldn't be worked out, usually because the toe point is out of
+	// reach, so it has to be assumed to hit something.
+	Unknown bool
+	// If Body is false, the other leg segment involved.
+	Body         bool
+	OtherLeg     LegPosition
+	OtherSegment Segment
+}
+
+func (c Collision) String() string {
+	if c.Unknown {
+		return fmt.Sprintf("leg %d %v has no valid position", c.Leg, c.Segment)
+	}
+	if c.Body {
+		return fmt.Sprintf("leg %d %v hits the body", c.Leg, c.Segment)
+	}
+	return fmt.Sprintf("leg %d %v hits leg %d %v", c.Leg, c.Segment, c.OtherLeg, c.OtherSegment)
+}
+
+// SetRejectCollisions controls whether SendCommandsToServos refuses to send frames which would cause a collision.
+func (s *Spider) SetRejectCollisions(reject bool) {
+	s.rejectCollisions = reject
+}
+
+// CheckCollisions returns every collision between the legs, and between the legs and the body, in the current pose.
+// Segments whose position is unknown, because a toe point is out of reach, are reported as collisions too.
+func (s *Spider) CheckCollisions() []Collision {
+	var segs [4][3][2]Point3D
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		l := &s.legs[leg]
+		bc, cf, ft := l.JointAngles()
+		pts := l.jointPoints([3]float64{bc, cf, ft})
+		for i := range segs[leg] {
+			segs[leg][i] = [2]Point3D{pts[i].Add(l.origin), pts[i+1].Add(l.origin)}
+		}
+	}
+
+	var collisions []Collision
+	// NaN distances fail every comparison, so segments with no valid position are reported, and then left out.
+	var unknown [4][3]bool
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		for seg := Coxa; seg <= Tibia; seg++ {
+			if p := segs[leg][seg]; !finite(p[0]) || !finite(p[1]) {
+				unknown[leg][seg] = true
+				collisions = append(collisions, Collision{Leg: leg, Segment: seg, Unknown: true})
+			}
+		}
+	}
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		// The coxa is mounted on a corner of the body, so its inner end always touches it. It only hits the body if
+		// it swings round far enough for its outer end to.
+		if p := segs[leg][Coxa]; !unknown[leg][Coxa] && pointBoxDistance(p[1]) < SegmentRadius {
+			collisions = append(collisions, Collision{Leg: leg, Segment: Coxa, Body: true})
+		}
+		for seg := Femur; seg <= Tibia; seg++ {
+			p := segs[leg][seg]
+			if !unknown[leg][seg] && segmentBoxDistance(p[0], p[1]) < SegmentRadius {
+				collisions = append(collisions, Collision{Leg: leg, Segment: seg, Body: true})
+			}
+		}
+		for other := leg + 1; other < LegPosition(4); other++ {
+			for seg := Coxa; seg <= Tibia; seg++ {
+				for otherSeg := Coxa; otherSeg <= Tibia; otherSeg++ {
+					p, q := segs[leg][seg], segs[other][otherSeg]
+					if unknown[leg][seg] || unknown[other][otherSeg] {
+						continue
+					}
+					if segmentDistance(p[0], p[1], q[0], q[1]) < 2*SegmentRadius {
+						collisions = append(collisions, Collision{Leg: leg, Segment: seg, OtherLeg: other, OtherSegment: otherSeg})
+					}
+				}
+			}
+		}
+	}
+	return collisions
+}
+
+// segmentDistance returns the shortest distance between the line segments p1-q1 and p2-q2.
+// See Ericson, Real-Time Collision Detection, section 5.1.9.
+func segmentDistance(p1, q1, p2, q2 Point3D) float64 {
+	d1 := q1.Sub(p1)
+	d2 := q2.Sub(p2)
+	r := p1.Sub(p2)
+	a := dot(d1, d1)
+	e := dot(d2, d2)
+	f := dot(d2, r)
+	var s, t float64
+	switch {
+	case a == 0 && e == 0:
+		// Both segments are points.
+	case a == 0:
+		t = clamp(f/e, 0, 1)
+	default:
+		c := dot(d1, r)
+		if e == 0 {
+			s = clamp(-c/a, 0, 1)
+			break
+		}
+		b := dot(d1, d2)
+		if denom := a*e - b*b; denom != 0 {
+			s = clamp((b*f-c*e)/denom, 0, 1)
+		}
+		t = (b*s + f) / e
+		if t < 0 {
+			t = 0
+			s = clamp(-c/a, 0, 1)
+		} else if t > 1 {
+			t = 1
+			s = clamp((b-c)/a, 0, 1)
+		}
+	}
+	return length(p1.Add(d1.Scale(s)).Sub(p2.Add(d2.Scale(t))))
+}
+
+// segmentBoxDistance returns the shortest distance between the line segment p-q and the body box.
+// The distance from a point on a line to a convex shape is a convex function, so a ternary search finds the minimum.
+func segmentBoxDistance(p, q Point3D) float64 {
+	at := func(u float64) float64 {
+		return pointBoxDistance(p.Add(q.Sub(p).Scale(u)))
+	}
+	lo, hi := 0.0, 1.0
+	for i := 0; i < 50; i++ {
+		m1 := lo + (hi-lo)/3
+		m2 := hi - (hi-lo)/3
+		if at(m1) < at(m2) {
+			hi = m2
+		} else {
+			lo = m1
+		}
+	}
+	return at((lo + hi) / 2)
+}
+
+// pointBoxDistance returns the distance from a body frame point to the body box, or zero if it is inside.
+func pointBoxDistance(pt Point3D) float64 {
+	outside := func(v, half float64) float64 {
+		return math.Max(math.Abs(v)-half, 0)
+	}
+	return length(Point3D{
+		X: outside(pt.X, BodyHalfWidth),
+		Y: outside(pt.Y, BodyHalfLength),
+		Z: outside(pt.Z, BodyHalfHeight),
+	})
+}
+
+// finite reports whether every coordinate of a point is a real number.
+func finite(a Point3D) bool {
+	for _, v := range [3]float64{a.X, a.Y, a.Z} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func dot(a, b Point3D) float64 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+func length(a Point3D) float64 {
+	return math.Sqrt(dot(a, a))
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
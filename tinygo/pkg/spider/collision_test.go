@@ -0,0 +1,153 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSegmentDistance(t *testing.T) {
+	tests := []struct {
+		p1, q1, p2, q2 Point3D
+		want           float64
+	}{
+		// Crossing at right angles, one above the other.
+		{Point3D{X: -1}, Point3D{X: 1}, Point3D{Y: -1, Z: 2}, Point3D{Y: 1, Z: 2}, 2},
+		// Parallel and overlapping.
+		{Point3D{}, Point3D{X: 4}, Point3D{X: 1, Y: 3}, Point3D{X: 6, Y: 3}, 3},
+		// Collinear, end to end.
+		{Point3D{}, Point3D{X: 1}, Point3D{X: 3}, Point3D{X: 5}, 2},
+		// The closest point is an end point of both.
+		{Point3D{}, Point3D{X: 1}, Point3D{X: 4, Y: 4}, Point3D{X: 4, Y: 8}, 5},
+		// A point and a segment.
+		{Point3D{X: 2, Y: 1}, Point3D{X: 2, Y: 1}, Point3D{}, Point3D{X: 4}, 1},
+		// Two points.
+		{Point3D{}, Point3D{}, Point3D{X: 3, Y: 4}, Point3D{X: 3, Y: 4}, 5},
+	}
+	for _, tt := range tests {
+		if got := segmentDistance(tt.p1, tt.q1, tt.p2, tt.q2); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("segmentDistance(%v, %v, %v, %v) = %v, want %v", tt.p1, tt.q1, tt.p2, tt.q2, got, tt.want)
+		}
+		if got := segmentDistance(tt.p2, tt.q2, tt.p1, tt.q1); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("segmentDistance(%v, %v, %v, %v) = %v, want %v", tt.p2, tt.q2, tt.p1, tt.q1, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentBoxDistance(t *testing.T) {
+	tests := []struct {
+		p, q Point3D
+		want float64
+	}{
+		{Point3D{X: -100}, Point3D{X: 100}, 0},
+		{Point3D{X: BodyHalfWidth + 5}, Point3D{X: BodyHalfWidth + 50}, 5},
+		{Point3D{X: -100, Z: -BodyHalfHeight - 3}, Point3D{X: 100, Z: -BodyHalfHeight - 3}, 3},
+		{Point3D{X: BodyHalfWidth + 3, Y: BodyHalfLength + 4, Z: 50}, Point3D{X: BodyHalfWidth + 3, Y: BodyHalfLength + 4, Z: -50}, 5},
+	}
+	for _, tt := range tests {
+		if got := segmentBoxDistance(tt.p, tt.q); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("segmentBoxDistance(%v, %v) = %v, want %v", tt.p, tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCollisions(t *testing.T) {
+	tests := []struct {
+		name string
+		feet [4]Point3D
+		want []Collision
+	}{
+		{"neutral", [4]Point3D{}, nil},
+		{
+			"right feet together",
+			[4]Point3D{FrontRight: {Y: -72}, BackRight: {Y: 72}},
+			[]Collision{
+				{Leg: FrontRight, Segment: Femur, OtherLeg: BackRight, OtherSegment: Femur},
+				{Leg: FrontRight, Segment: Femur, OtherLeg: BackRight, OtherSegment: Tibia},
+				{Leg: FrontRight, Segment: Tibia, OtherLeg: BackRight, OtherSegment: Femur},
+				{Leg: FrontRight, Segment: Tibia, OtherLeg: BackRight, OtherSegment: Tibia},
+			},
+		},
+		{
+			"foot under the body",
+			[4]Point3D{FrontRight: {X: -80, Y: -80}},
+			[]Collision{
+				{Leg: FrontRight, Segment: Coxa, Body: true},
+				{Leg: FrontRight, Segment: Femur, Body: true},
+				{Leg: FrontRight, Segment: Tibia, Body: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		s := newTestSpider()
+		for leg, pt := range tt.feet {
+			s.SetToePoint(LegPosition(leg), pt)
+		}
+		got := s.CheckCollisions()
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: CheckCollisions() = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: CheckCollisions() = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestRejectCollisions(t *testing.T) {
+	r := NewRecorder()
+	s := New(r, DefaultConfig())
+	s.SetToePoint(FrontRight, Point3D{X: -80, Y: -80})
+	s.SendCommandsToServos()
+	if len(r.Frames) != 1 {
+		t.Fatalf("frames sent without collision checking = %d, want 1", len(r.Frames))
+	}
+
+	s.SetRejectCollisions(true)
+	err := s.SendCommandsToServos()
+	if !errors.Is(err, ErrCollision) {
+		t.Errorf("SendCommandsToServos() = %v, want ErrCollision", err)
+	}
+	if len(r.Frames) != 1 || s.FrameStatus().Delivered {
+		t.Errorf("a frame with a collision was sent")
+	}
+	if s.SafeStopped() {
+		t.Errorf("rejected frames tripped a safe stop")
+	}
+
+	s.SetToePoint(FrontRight, Point3D{})
+	if err := s.SendCommandsToServos(); errors.Is(err, ErrCollision) || len(r.Frames) != 2 {
+		t.Errorf("SendCommandsToServos() = %v after clearing the collision, want the frame sent", err)
+	}
+}
+
+func TestCheckCollisionsUnreachable(t *testing.T) {
+	s := newTestSpider()
+	s.SetToePoint(BackLeft, Point3D{Z: -500})
+	collisions := s.CheckCollisions()
+	if len(collisions) == 0 {
+		t.Fatal("CheckCollisions() found nothing with an unreachable toe point")
+	}
+	for _, c := range collisions {
+		if !c.Unknown || c.Leg != BackLeft {
+			t.Errorf("CheckCollisions() reported %v, want only the back left leg's unknown segments", c)
+		}
+	}
+}
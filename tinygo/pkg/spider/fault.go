@@ -0,0 +1,172 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrSafeStop = errors.New("spider: stopped after repeated actuator faults")
+
+// ErrOutOfRange is reported in a JointError when a joint angle had to be clamped to its servo's range.
+var ErrOutOfRange = errors.New("joint angle is outside the servo's range")
+
+// ErrInvalidAngle is reported in a JointError when a joint angle is NaN or infinite, usually because a toe point is
+// out of reach. A frame containing one is never sent.
+var ErrInvalidAngle = errors.New("joint angle is not finite")
+
+// FaultPolicy says what SendCommandsToServos does when the actuator returns an error.
+// A frame is resent up to Retries times, and then skipped. After TripAfter consecutive skipped frames,
+// the Spider stops all motion, turns off the servos and refuses to send any more frames until ClearFault is called.
+// A TripAfter of zero never trips.
+// A skipped frame may still have been partly applied: actuators such as the PCA9685 move each servo as soon as
+// SetPulse is called, so the joints before the one that failed will already have moved.
+type FaultPolicy struct {
+	Retries   int
+	TripAfter int
+}
+
+var DefaultFaultPolicy = FaultPolicy{
+	Retries:   1,
+	TripAfter: 5,
+}
+
+// JointError is a failure to command a single joint.
+type JointError struct {
+	Leg   LegPosition
+	Joint Joint
+	Err   error
+}
+
+func (e JointError) Error() string {
+	return fmt.Sprintf("leg %d joint %d: %v", e.Leg, e.Joint, e.Err)
+}
+
+func (e JointError) Unwrap() error {
+	return e.Err
+}
+
+// FrameError collects everything that went wrong while sending a frame.
+type FrameError struct {
+	Joints []JointError
+	// Error returned by the actuator's Commit, if any.
+	Commit error
+}
+
+func (e *FrameError) Error() string {
+	var msgs []string
+	for _, j := range e.Joints {
+		msgs = append(msgs, j.Error())
+	}
+	if e.Commit != nil {
+		msgs = append(msgs, fmt.Sprintf("commit: %v", e.Commit))
+	}
+	return "spider: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the joint or commit errors matches the target.
+func (e *FrameError) Is(target error) bool {
+	for _, j := range e.Joints {
+		if errors.Is(j.Err, target) {
+			return true
+		}
+	}
+	return errors.Is(e.Commit, target)
+}
+
+// actuatorFailed reports whether the actuator rejected any part of the frame, as opposed to the frame merely
+// containing clamped angles.
+func (e *FrameError) actuatorFailed() bool {
+	if e.Commit != nil {
+		return true
+	}
+	for _, j := range e.Joints {
+		if j.Err != ErrOutOfRange {
+			return true
+		}
+	}
+	return false
+}
+
+// FrameStatus describes the most recent call to SendCommandsToServos.
+type FrameStatus struct {
+	// Delivered is true if the actuator accepted the whole frame.
+	Delivered bool
+	// Attempts is the number of times the frame was sent, including retries.
+	Attempts int
+	// Err is the error returned for the frame, if any.
+	Err error
+}
+
+func (s *Spider) SetFaultPolicy(p FaultPolicy) {
+	s.faults = p
+}
+
+func (s *Spider) FrameStatus() FrameStatus {
+	return s.frame
+}
+
+// SafeStopped reports whether repeated actuator faults have stopped the Spider.
+func (s *Spider) SafeStopped() bool {
+	return s.stopped
+}
+
+// ClearFault leaves the safe-stop state, and counts the faulted legs as standing. The next frame is sent normally.
+func (s *Spider) ClearFault() {
+	s.stopped = false
+	s.failures = 0
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if s.legs[leg].state == Faulted {
+			s.legs[leg].setState(Stance)
+		}
+	}
+}
+
+// sendFrame sends one set of pulses to the actuator, returning a *FrameError if anything went wrong.
+func (s *Spider) sendFrame(pulses *[12]uint16, inRange *[12]bool) error {
+	fe := &FrameError{}
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		for joint := BodyCoxa; joint <= FemurTibia; joint++ {
+			id := servoId(leg, joint)
+			if err := s.act.SetPulse(s.servos[id].Pin(), pulses[id]); err != nil {
+				fe.Joints = append(fe.Joints, JointError{Leg: leg, Joint: joint, Err: err})
+			} else if !inRange[id] {
+				fe.Joints = append(fe.Joints, JointError{Leg: leg, Joint: joint, Err: ErrOutOfRange})
+			}
+		}
+	}
+	fe.Commit = s.act.Commit()
+	if len(fe.Joints) == 0 && fe.Commit == nil {
+		return nil
+	}
+	return fe
+}
+
+// safeStop stops all motion and makes a best-effort attempt to turn off the servos.
+func (s *Spider) safeStop() {
+	s.stopped = true
+	s.move = nil
+	s.queue = nil
+	s.stopWalking()
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		s.legs[leg].setState(Faulted)
+	}
+	for _, servo := range s.servos {
+		s.act.SetPulse(servo.Pin(), 0)
+	}
+	s.act.Commit()
+}
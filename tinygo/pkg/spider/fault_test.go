@@ -0,0 +1,178 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"errors"
+	"testing"
+)
+
+var errBus = errors.New("bus error")
+
+// flakyActuator records like a Recorder, but fails a number of calls on one channel.
+type flakyActuator struct {
+	*Recorder
+	channel  byte
+	failures int
+}
+
+func (a *flakyActuator) SetPulse(channel byte, micros uint16) error {
+	if channel == a.channel && micros != 0 && a.failures > 0 {
+		a.failures--
+		return errBus
+	}
+	return a.Recorder.SetPulse(channel, micros)
+}
+
+func newFlakySpider(policy FaultPolicy, failures int) (*Spider, *flakyActuator) {
+	act := &flakyActuator{Recorder: NewRecorder(), channel: servoId(BackRight, CoxaFemur), failures: failures}
+	config := DefaultConfig()
+	config.Faults = policy
+	return New(act, config), act
+}
+
+func TestSendCommandsToServosReportsFailingJoint(t *testing.T) {
+	s, _ := newFlakySpider(FaultPolicy{}, 1)
+	err := s.SendCommandsToServos()
+	var fe *FrameError
+	if !errors.As(err, &fe) {
+		t.Fatalf("SendCommandsToServos() = %v, want a *FrameError", err)
+	}
+	if len(fe.Joints) != 1 || fe.Joints[0].Leg != BackRight || fe.Joints[0].Joint != CoxaFemur {
+		t.Errorf("SendCommandsToServos() failed joints = %+v, want BackRight CoxaFemur", fe.Joints)
+	}
+	if !errors.Is(err, errBus) {
+		t.Errorf("SendCommandsToServos() = %v, want it to wrap the actuator error", err)
+	}
+	if st := s.FrameStatus(); st.Delivered || st.Attempts != 1 {
+		t.Errorf("FrameStatus() = %+v, want one undelivered attempt", st)
+	}
+}
+
+func TestSendCommandsToServosRetries(t *testing.T) {
+	tests := []struct {
+		retries       int
+		failures      int
+		wantDelivered bool
+		wantAttempts  int
+	}{
+		{0, 1, false, 1},
+		{1, 1, true, 2},
+		{2, 1, true, 2},
+		{2, 3, false, 3},
+	}
+	for _, tt := range tests {
+		s, act := newFlakySpider(FaultPolicy{Retries: tt.retries}, tt.failures)
+		err := s.SendCommandsToServos()
+		st := s.FrameStatus()
+		if st.Delivered != tt.wantDelivered || st.Attempts != tt.wantAttempts {
+			t.Errorf("%d retries, %d failures: FrameStatus() = %+v, want delivered=%v after %d attempts",
+				tt.retries, tt.failures, st, tt.wantDelivered, tt.wantAttempts)
+		}
+		if tt.wantDelivered && err != nil && !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("%d retries, %d failures: SendCommandsToServos() = %v after a delivered frame", tt.retries, tt.failures, err)
+		}
+		if tt.wantDelivered && act.Pulse(act.channel) == 0 {
+			t.Errorf("%d retries, %d failures: failing channel was never delivered", tt.retries, tt.failures)
+		}
+	}
+}
+
+func TestSendCommandsToServosReportsOutOfRange(t *testing.T) {
+	s := newTestSpider()
+	// Reachable, but further round than the body-coxa servo can turn.
+	s.SetToePoint(FrontLeft, Point3D{X: 40})
+	err := s.SendCommandsToServos()
+	var fe *FrameError
+	if !errors.As(err, &fe) || !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("SendCommandsToServos() = %v, want an out of range *FrameError", err)
+	}
+	for _, j := range fe.Joints {
+		if j.Leg != FrontLeft {
+			t.Errorf("SendCommandsToServos() reported leg %d, want only FrontLeft", j.Leg)
+		}
+	}
+	if !s.FrameStatus().Delivered {
+		t.Error("a frame with clamped angles was not delivered")
+	}
+}
+
+func TestSafeStop(t *testing.T) {
+	s, act := newFlakySpider(FaultPolicy{TripAfter: 3}, 1000)
+	s.SetVelocity(10, 0, 0)
+	for i := 0; i < 2; i++ {
+		s.SendCommandsToServos()
+	}
+	if s.SafeStopped() {
+		t.Fatal("SafeStopped() = true before TripAfter consecutive failures")
+	}
+	s.SendCommandsToServos()
+	if !s.SafeStopped() {
+		t.Fatal("SafeStopped() = false after TripAfter consecutive failures")
+	}
+	if s.Walking() {
+		t.Error("Walking() = true after a safe stop")
+	}
+	for _, servo := range s.servos {
+		if got := act.Pulse(servo.Pin()); got != 0 {
+			t.Errorf("channel %d pulse = %d after a safe stop, want 0", servo.Pin(), got)
+		}
+	}
+	if err := s.SendCommandsToServos(); err != ErrSafeStop {
+		t.Errorf("SendCommandsToServos() while stopped = %v, want ErrSafeStop", err)
+	}
+
+	act.failures = 0
+	s.ClearFault()
+	s.SendCommandsToServos()
+	if s.SafeStopped() || !s.FrameStatus().Delivered {
+		t.Errorf("frame after ClearFault() was not delivered: %+v", s.FrameStatus())
+	}
+}
+
+func TestSafeStopNeedsConsecutiveFailures(t *testing.T) {
+	s, act := newFlakySpider(FaultPolicy{TripAfter: 2}, 1)
+	for i := 0; i < 5; i++ {
+		s.SendCommandsToServos()
+		act.failures = i % 2
+	}
+	if s.SafeStopped() {
+		t.Error("SafeStopped() = true after alternating failures")
+	}
+}
+
+func TestSendCommandsToServosRejectsInvalidAngles(t *testing.T) {
+	r := NewRecorder()
+	s := New(r, DefaultConfig())
+	s.SetToePoint(BackLeft, Point3D{Z: -500})
+	err := s.SendCommandsToServos()
+	if !errors.Is(err, ErrInvalidAngle) {
+		t.Fatalf("SendCommandsToServos() with an unreachable foot = %v, want ErrInvalidAngle", err)
+	}
+	var fe *FrameError
+	if errors.As(err, &fe) {
+		for _, j := range fe.Joints {
+			if j.Leg != BackLeft {
+				t.Errorf("SendCommandsToServos() reported %v, want only the back left leg", j)
+			}
+		}
+	}
+	if len(r.Frames) != 0 || len(r.pending) != 0 {
+		t.Errorf("the actuator was sent %d frames and %d pulses, want none", len(r.Frames), len(r.pending))
+	}
+	if st := s.FrameStatus(); st.Delivered {
+		t.Errorf("FrameStatus() = %+v, want the frame to be undelivered", st)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+)
+
+// Single precision kinematics for microcontrollers such as the nRF52840, which only have a single precision FPU and
+// would otherwise emulate the float64 math package in software. The trig functions are polynomial approximations,
+// accurate to better than 1e-4 radians, which is far finer than a hobby servo can resolve.
+// Build with the spider_f32 tag to use them in place of the float64 versions.
+// float32_test.go checks this path against the float64 one in either build.
+
+const (
+	pi32          = float32(math.Pi)
+	radToDeg32    = float32(180 / math.Pi)
+	coxaLength32  = float32(CoxaLength)
+	femurLength32 = float32(FemurLength)
+	tibiaLength32 = float32(TibiaLength)
+)
+
+var nan32 = float32(math.NaN())
+
+// legTriangle32 is the single precision version of legTriangle64.
+func legTriangle32(dx, dy, dz float32) (float32, float32, float32, float32) {
+	bodyCoxaAngle := atan2f32(dy, dx)
+	ftHorizReach := sqrtf32(dx*dx+dy*dy) - coxaLength32
+	ftReach := sqrtf32(ftHorizReach*ftHorizReach + dz*dz)
+	femurReachAngle := acosf32((ftReach*ftReach + femurLength32*femurLength32 - tibiaLength32*tibiaLength32) / (2 * ftReach * femurLength32))
+	reachAngle := atan2f32(dz, ftHorizReach)
+	femurTibiaAngle := acosf32((femurLength32*femurLength32 + tibiaLength32*tibiaLength32 - ftReach*ftReach) / (2 * femurLength32 * tibiaLength32))
+	return bodyCoxaAngle, reachAngle, femurReachAngle, femurTibiaAngle
+}
+
+// radiansToDegrees32 converts an angle to whole degrees, rounding half away from zero like math.Round.
+func radiansToDegrees32(rad float32) int16 {
+	deg := rad * radToDeg32
+	if deg < 0 {
+		return int16(deg - 0.5)
+	}
+	return int16(deg + 0.5)
+}
+
+// sqrtf32 starts from the classic bit-twiddling estimate and refines it with Newton's method.
+func sqrtf32(x float32) float32 {
+	if x <= 0 {
+		if x == 0 {
+			return 0
+		}
+		return nan32
+	}
+	y := math.Float32frombits(0x1fbd1df5 + math.Float32bits(x)>>1)
+	for i := 0; i < 3; i++ {
+		y = 0.5 * (y + x/y)
+	}
+	return y
+}
+
+// atan2f32 reduces the angle to [0, Pi/4] and uses a minimax polynomial for atan.
+func atan2f32(y, x float32) float32 {
+	ax, ay := x, y
+	if ax < 0 {
+		ax = -ax
+	}
+	if ay < 0 {
+		ay = -ay
+	}
+	if ax == 0 && ay == 0 {
+		return 0
+	}
+	var z float32
+	swapped := ay > ax
+	if swapped {
+		z = ax / ay
+	} else {
+		z = ay / ax
+	}
+	z2 := z * z
+	a := z * (0.99997726 + z2*(-0.33262347+z2*(0.19354346+z2*(-0.11643287+z2*(0.05265332+z2*-0.01172120)))))
+	if swapped {
+		a = pi32/2 - a
+	}
+	if x < 0 {
+		a = pi32 - a
+	}
+	if y < 0 {
+		a = -a
+	}
+	return a
+}
+
+// acosf32 uses the approximation from Abramowitz and Stegun, 4.4.46.
+func acosf32(x float32) float32 {
+	if x > 1 || x < -1 || x != x {
+		return nan32
+	}
+	neg := x < 0
+	if neg {
+		x = -x
+	}
+	a := sqrtf32(1-x) * (1.5707963050 + x*(-0.2145988016+x*(0.0889789874+x*(-0.0501743046+x*(0.0308918810+x*(-0.0170881256+x*(0.0066700901+x*-0.0012624911)))))))
+	if neg {
+		return pi32 - a
+	}
+	return a
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSqrtf32(t *testing.T) {
+	for _, x := range []float64{0, 1e-6, 0.25, 1, 2, 10, 1234.5, 1e6} {
+		got := float64(sqrtf32(float32(x)))
+		if want := math.Sqrt(x); math.Abs(got-want) > 1e-6*math.Max(want, 1) {
+			t.Errorf("sqrtf32(%v) = %v, want %v", x, got, want)
+		}
+	}
+	if got := sqrtf32(-1); got == got {
+		t.Errorf("sqrtf32(-1) = %v, want NaN", got)
+	}
+}
+
+func TestAtan2f32(t *testing.T) {
+	for a := -math.Pi; a <= math.Pi; a += 0.01 {
+		for _, r := range []float64{0.1, 1, 100} {
+			y, x := r*math.Sin(a), r*math.Cos(a)
+			got := float64(atan2f32(float32(y), float32(x)))
+			want := math.Atan2(y, x)
+			if d := math.Abs(math.Remainder(got-want, 2*math.Pi)); d > 1e-4 {
+				t.Errorf("atan2f32(%v, %v) = %v, want %v", y, x, got, want)
+			}
+		}
+	}
+	if got := atan2f32(0, 0); got != 0 {
+		t.Errorf("atan2f32(0, 0) = %v, want 0", got)
+	}
+}
+
+func TestAcosf32(t *testing.T) {
+	for x := -1.0; x <= 1.0; x += 0.001 {
+		got := float64(acosf32(float32(x)))
+		if want := math.Acos(x); math.Abs(got-want) > 1e-4 {
+			t.Errorf("acosf32(%v) = %v, want %v", x, got, want)
+		}
+	}
+	for _, x := range []float32{-1.01, 1.01, nan32} {
+		if got := acosf32(x); got == got {
+			t.Errorf("acosf32(%v) = %v, want NaN", x, got)
+		}
+	}
+}
+
+func TestLegTriangle32MatchesFloat64(t *testing.T) {
+	var worst float64
+	for dx := -150.0; dx <= 150; dx += 7.5 {
+		for dy := -150.0; dy <= 150; dy += 7.5 {
+			for dz := -150.0; dz <= 50; dz += 7.5 {
+				var want, got [4]float64
+				want[0], want[1], want[2], want[3] = legTriangle64(dx, dy, dz)
+				g0, g1, g2, g3 := legTriangle32(float32(dx), float32(dy), float32(dz))
+				got = [4]float64{float64(g0), float64(g1), float64(g2), float64(g3)}
+				for i := range want {
+					if math.IsNaN(want[i]) != math.IsNaN(got[i]) {
+						t.Fatalf("legTriangle32(%v, %v, %v) = %v, want %v", dx, dy, dz, got, want)
+					}
+					if d := math.Abs(got[i] - want[i]); d > worst {
+						worst = d
+					}
+				}
+			}
+		}
+	}
+	// Near the edge of the workspace acos is steep, so allow a little more than the trig error.
+	if worst > 2e-3 {
+		t.Errorf("legTriangle32 differs from legTriangle64 by up to %v radians", worst)
+	}
+}
+
+func TestRadiansToDegrees32(t *testing.T) {
+	for rad := -4.0; rad <= 4; rad += 0.001 {
+		want := int16(math.Round(rad / math.Pi * 180))
+		got := radiansToDegrees32(float32(rad))
+		if d := got - want; d < -1 || d > 1 {
+			t.Errorf("radiansToDegrees32(%v) = %v, want %v", rad, got, want)
+		} else if d != 0 && math.Abs(math.Abs(math.Mod(rad/math.Pi*180, 1))-0.5) > 1e-4 {
+			// Only a value right on a half degree may round the other way.
+			t.Errorf("radiansToDegrees32(%v) = %v, want %v", rad, got, want)
+		}
+	}
+}
+
+var benchSink float64
+
+func BenchmarkLegTriangle64(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		bc, reach, femurReach, ft := legTriangle64(40, 30+float64(i&15), -60)
+		benchSink += bc + reach + femurReach + ft
+	}
+}
+
+func BenchmarkLegTriangle32(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		bc, reach, femurReach, ft := legTriangle32(40, 30+float32(i&15), -60)
+		benchSink += float64(bc + reach + femurReach + ft)
+	}
+}
+
+func BenchmarkJointAngles(b *testing.B) {
+	s := newTestSpider()
+	l := &s.legs[FrontRight]
+	for i := 0; i < b.N; i++ {
+		l.SetToePoint(Point3D{X: float64(i & 15), Y: 5, Z: -10})
+		bc, cf, ft := l.JointAngles()
+		benchSink += bc + cf + ft
+	}
+}
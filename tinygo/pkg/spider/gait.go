@@ -0,0 +1,168 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"time"
+)
+
+// GaitParams describes the shape of a walking gait.
+// Distances are in the same units as Point3D.
+type GaitParams struct {
+	// Distance each foot travels, front to back, during one cycle.
+	StrideLength float64
+	// How high each foot is lifted during its swing.
+	StepHeight float64
+	// Height of the hips above the ground.
+	BodyHeight float64
+	// Time taken for every leg to take one step.
+	Period time.Duration
+	// Path of the foot while it is in the air. If nil, CycloidSwing is used.
+	Swing SwingTrajectory
+}
+
+// Gait generates toe points for walking, one tick at a time.
+// Toe points are in the world frame, relative to each leg's canonical zero toe point.
+type Gait interface {
+	// Update advances the gait by the given amount of time.
+	Update(dt time.Duration)
+	// InSwing reports whether the leg is currently in the air.
+	InSwing(leg LegPosition) bool
+	// ToePoint returns the current toe point for a leg.
+	ToePoint(leg LegPosition) Point3D
+}
+
+// Legs are lifted one at a time, in this order, so that the body only ever has to lean diagonally.
+var creepOrder = [4]LegPosition{BackRight, FrontRight, BackLeft, FrontLeft}
+
+// Fraction of each leg's quarter of the cycle spent shifting the body, before the leg is lifted.
+const creepShiftFraction = 0.5
+
+// How far towards the centroid of the support triangle to shift the body.
+// Going all the way gives the most margin, but leaves less reach for the feet.
+const creepLean = 0.5
+
+// CreepGait is a statically stable gait, where one leg swings at a time and the body is first shifted over the
+// triangle formed by the other three feet.
+type CreepGait struct {
+	params GaitParams
+	// Fraction of the way through the cycle, in [0, 1).
+	phase float64
+	// Where the body is shifted during each quarter of the cycle.
+	shifts [4]Point3D
+}
+
+func NewCreepGait(params GaitParams) *CreepGait {
+	g := &CreepGait{
+		params: params,
+	}
+	// Lean the body towards the centroid of the support triangle, as it will be half way through the swing.
+	var legs [4]Leg
+	for i := range legs {
+		legs[i].init(LegPosition(i))
+	}
+	for q, lifted := range creepOrder {
+		start, end := g.swingWindow(lifted)
+		var c Point3D
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if leg != lifted {
+				c = c.Add(legs[leg].origin).Add(g.strideToePoint(leg, (start+end)/2))
+			}
+		}
+		g.shifts[q] = Point3D{X: c.X / 3, Y: c.Y / 3}.Scale(creepLean)
+	}
+	return g
+}
+
+// Update advances the gait by the given amount of time.
+func (g *CreepGait) Update(dt time.Duration) {
+	if g.params.Period <= 0 {
+		return
+	}
+	g.phase += float64(dt) / float64(g.params.Period)
+	g.phase -= math.Floor(g.phase)
+}
+
+func (g *CreepGait) Phase() float64 {
+	return g.phase
+}
+
+// InSwing reports whether the leg is currently in the air.
+func (g *CreepGait) InSwing(leg LegPosition) bool {
+	start, end := g.swingWindow(leg)
+	return g.phase >= start && g.phase < end
+}
+
+// ToePoint returns the current toe point for a leg.
+func (g *CreepGait) ToePoint(leg LegPosition) Point3D {
+	return g.strideToePoint(leg, g.phase).Sub(g.BodyShift())
+}
+
+// BodyShift returns how far the body is currently shifted over the support triangle.
+// It is already included in the toe points.
+func (g *CreepGait) BodyShift() Point3D {
+	q := int(g.phase * 4)
+	u := (g.phase*4 - float64(q)) / creepShiftFraction
+	if u >= 1 {
+		return g.shifts[q]
+	}
+	// Ease from the previous quarter's shift so that the body doesn't jerk.
+	from := g.shifts[(q+3)%4]
+	return from.Add(g.shifts[q].Sub(from).Scale(smoothstep(u)))
+}
+
+// strideToePoint returns the toe point for a leg at the given phase, ignoring the body shift.
+func (g *CreepGait) strideToePoint(leg LegPosition, phase float64) Point3D {
+	start, end := g.swingWindow(leg)
+	return stridePoint(g.params, 1-(end-start), phase-end)
+}
+
+// swingWindow returns the start and end phase of the leg's swing.
+func (g *CreepGait) swingWindow(leg LegPosition) (float64, float64) {
+	for q, lifted := range creepOrder {
+		if lifted == leg {
+			return (float64(q) + creepShiftFraction) / 4, float64(q+1) / 4
+		}
+	}
+	return 0, 0
+}
+
+// stridePoint returns the toe point for a leg which touched down the given fraction of a cycle ago,
+// and which spends dutyFactor of each cycle in stance.
+func stridePoint(params GaitParams, dutyFactor, sinceTouchdown float64) Point3D {
+	sinceTouchdown -= math.Floor(sinceTouchdown)
+	stride := params.StrideLength
+	pt := Point3D{Z: TibiaLength - params.BodyHeight}
+	if sinceTouchdown < dutyFactor {
+		// Slide backwards at a constant speed, from touchdown until lift-off.
+		pt.Y = stride * (0.5 - sinceTouchdown/dutyFactor)
+		return pt
+	}
+	// Swing forwards, from the back of the stride to the front.
+	u := (sinceTouchdown - dutyFactor) / (1 - dutyFactor)
+	from, to := pt, pt
+	from.Y = -stride / 2
+	to.Y = stride / 2
+	return params.swing(from, to, u)
+}
+
+// swing returns the position of a foot in the air.
+func (p GaitParams) swing(from, to Point3D, u float64) Point3D {
+	if p.Swing == nil {
+		return CycloidSwing(from, to, p.StepHeight, u)
+	}
+	return p.Swing(from, to, p.StepHeight, u)
+}
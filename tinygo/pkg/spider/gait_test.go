@@ -0,0 +1,178 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var testGaitParams = GaitParams{
+	StrideLength: 30,
+	StepHeight:   15,
+	BodyHeight:   70,
+	Period:       4 * time.Second,
+}
+
+// originInsideTriangle reports whether the origin is strictly inside the XY projection of the triangle.
+func originInsideTriangle(a, b, c Point3D) bool {
+	cross := func(p, q Point3D) float64 {
+		return p.X*q.Y - p.Y*q.X
+	}
+	d1, d2, d3 := cross(a, b), cross(b, c), cross(c, a)
+	return (d1 > 0 && d2 > 0 && d3 > 0) || (d1 < 0 && d2 < 0 && d3 < 0)
+}
+
+func TestCreepGaitOneLegSwingsAtATime(t *testing.T) {
+	g := NewCreepGait(testGaitParams)
+	counts := [4]int{}
+	for i := 0; i < 400; i++ {
+		swinging := 0
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if g.InSwing(leg) {
+				swinging++
+				counts[leg]++
+			}
+		}
+		if swinging > 1 {
+			t.Fatalf("at phase %v, %d legs are swinging", g.Phase(), swinging)
+		}
+		g.Update(10 * time.Millisecond)
+	}
+	for leg, n := range counts {
+		if n == 0 {
+			t.Errorf("leg %v never swung", leg)
+		}
+	}
+}
+
+func TestCreepGaitTrajectoriesAreContinuousAndPeriodic(t *testing.T) {
+	g := NewCreepGait(testGaitParams)
+	var start, prev [4]Point3D
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		start[leg] = g.ToePoint(leg)
+		prev[leg] = start[leg]
+	}
+	for i := 0; i < 400; i++ {
+		g.Update(10 * time.Millisecond)
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			pt := g.ToePoint(leg)
+			if d := pt.Sub(prev[leg]); math.Sqrt(d.X*d.X+d.Y*d.Y+d.Z*d.Z) > 2 {
+				t.Errorf("leg %v jumped from %v to %v at phase %v", leg, prev[leg], pt, g.Phase())
+			}
+			prev[leg] = pt
+		}
+	}
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if !approxEqualWithin(prev[leg], start[leg], 1e-6) {
+			t.Errorf("leg %v ended the cycle at %v, want %v", leg, prev[leg], start[leg])
+		}
+	}
+}
+
+func TestCreepGaitStanceFeetOnGroundAndMovingBack(t *testing.T) {
+	g := NewCreepGait(testGaitParams)
+	groundZ := TibiaLength - testGaitParams.BodyHeight
+	for i := 0; i < 400; i++ {
+		var pts [4]Point3D
+		var stance [4]bool
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			pts[leg] = g.ToePoint(leg).Add(g.BodyShift())
+			stance[leg] = !g.InSwing(leg)
+		}
+		g.Update(10 * time.Millisecond)
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if !stance[leg] || g.InSwing(leg) {
+				continue
+			}
+			if pts[leg].Z != groundZ {
+				t.Errorf("stance leg %v is at height %v, want %v", leg, pts[leg].Z, groundZ)
+			}
+			if pt := g.ToePoint(leg).Add(g.BodyShift()); pt.Y > pts[leg].Y {
+				t.Errorf("stance leg %v moved forward from %v to %v", leg, pts[leg], pt)
+			}
+		}
+	}
+}
+
+func TestCreepGaitBodyOverSupportTriangle(t *testing.T) {
+	g := NewCreepGait(testGaitParams)
+	var legs [4]Leg
+	for i := range legs {
+		legs[i].init(LegPosition(i))
+	}
+	for i := 0; i < 400; i++ {
+		var support []Point3D
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if !g.InSwing(leg) {
+				support = append(support, g.ToePoint(leg).Add(legs[leg].origin))
+			}
+		}
+		if len(support) == 3 && !originInsideTriangle(support[0], support[1], support[2]) {
+			t.Errorf("at phase %v, the body is outside the support triangle %v", g.Phase(), support)
+		}
+		g.Update(10 * time.Millisecond)
+	}
+}
+
+func TestApplyGait(t *testing.T) {
+	s := newTestSpider()
+	g := NewCreepGait(testGaitParams)
+	swung := false
+	for i := 0; i < 400; i++ {
+		g.Update(10 * time.Millisecond)
+		s.ApplyGait(g, 10*time.Millisecond)
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if got, want := s.ToePoint(leg), g.ToePoint(leg); got != want {
+				t.Fatalf("leg %v toe point is %v, want %v", leg, got, want)
+			}
+			want := Stance
+			if g.InSwing(leg) {
+				want = Swing
+				swung = true
+			}
+			if got := s.LegState(leg); got != want {
+				t.Fatalf("at phase %v, leg %v is in state %v, want %v", g.Phase(), leg, got, want)
+			}
+		}
+	}
+	if !swung {
+		t.Error("no leg swung during a whole cycle")
+	}
+}
+
+// sliceGait is a gait which can't be compared with ==.
+type sliceGait struct {
+	*CreepGait
+	unused []int
+}
+
+func TestApplyGaitSwitchingGaits(t *testing.T) {
+	s := newTestSpider()
+	a := NewCreepGait(testGaitParams)
+	a.Update(time.Second)
+	s.ApplyGait(sliceGait{CreepGait: a}, time.Second)
+	s.ApplyGait(sliceGait{CreepGait: a}, time.Second)
+
+	// A new gait, whose feet are somewhere else, starts afresh after ResetGait.
+	b := NewCreepGait(testGaitParams)
+	b.Update(3 * time.Second)
+	s.ResetGait()
+	s.ApplyGait(b, time.Second)
+	if got := s.Odometry(); got != (Odometry{}) {
+		t.Errorf("Odometry() after switching gaits = %+v, want zero", got)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+)
+
+// Damping used when converting foot velocities to joint velocities, in distance units.
+// It only matters close to a singularity, where it trades tracking accuracy for bounded joint speeds.
+const jacobianDamping = 0.5
+
+// Manipulability below which a leg is considered to be close to a singularity.
+const SingularityThreshold = 0.1
+
+// Jacobian returns the partial derivatives of the toe position with respect to the body-coxa, coxa-femur,
+// and femur-tibia angles. Rows are X, Y, and Z; columns are joints.
+func (l *Leg) Jacobian(angles [3]float64) [3][3]float64 {
+	bc, cf, ft := angles[0], angles[1], angles[2]
+	tibia := cf + ft - math.Pi
+	horizReach := CoxaLength + FemurLength*math.Cos(cf) + TibiaLength*math.Cos(tibia)
+
+	// Derivatives of the horizontal reach and height with respect to the coxa-femur and femur-tibia angles.
+	drFT := -TibiaLength * math.Sin(tibia)
+	dzFT := TibiaLength * math.Cos(tibia)
+	drCF := drFT - FemurLength*math.Sin(cf)
+	dzCF := dzFT + FemurLength*math.Cos(cf)
+
+	cosBC, sinBC := math.Cos(bc), math.Sin(bc)
+	return [3][3]float64{
+		{-horizReach * sinBC, drCF * cosBC, drFT * cosBC},
+		{horizReach * cosBC, drCF * sinBC, drFT * sinBC},
+		{0, dzCF, dzFT},
+	}
+}
+
+// JointVelocities returns the joint angular velocities which move the toe with the given velocity.
+// Near a singularity the result is damped, so the toe will lag behind rather than the joints spinning up.
+func (l *Leg) JointVelocities(angles [3]float64, toeVel Point3D) [3]float64 {
+	return dampedLeastSquares(l.Jacobian(angles), [3]float64{toeVel.X, toeVel.Y, toeVel.Z}, jacobianDamping)
+}
+
+// ToeVelocity returns the toe velocity resulting from the given joint angular velocities.
+func (l *Leg) ToeVelocity(angles [3]float64, jointVel [3]float64) Point3D {
+	j := l.Jacobian(angles)
+	return Point3D{
+		X: j[0][0]*jointVel[0] + j[0][1]*jointVel[1] + j[0][2]*jointVel[2],
+		Y: j[1][0]*jointVel[0] + j[1][1]*jointVel[1] + j[1][2]*jointVel[2],
+		Z: j[2][0]*jointVel[0] + j[2][1]*jointVel[1] + j[2][2]*jointVel[2],
+	}
+}
+
+// JointTorques returns the joint torques needed to resist the given force applied at the toe, e.g. the ground reaction
+// force of a leg in stance. The units are the product of the force and distance units.
+func (l *Leg) JointTorques(angles [3]float64, toeForce Point3D) [3]float64 {
+	j := l.Jacobian(angles)
+	var torques [3]float64
+	for k := 0; k < 3; k++ {
+		torques[k] = j[0][k]*toeForce.X + j[1][k]*toeForce.Y + j[2][k]*toeForce.Z
+	}
+	return torques
+}
+
+// Manipulability returns the volume of the toe velocity ellipsoid, normalized so that 1.0 is the theoretical maximum.
+// It drops to zero when the knee is straight (or fully folded) or when the toe is directly below the body-coxa joint.
+func (l *Leg) Manipulability(angles [3]float64) float64 {
+	return math.Abs(det3(l.Jacobian(angles))) / ((CoxaLength + FemurLength + TibiaLength) * FemurLength * TibiaLength)
+}
+
+// NearSingularity reports whether the leg is close enough to a singularity that Cartesian velocity control will suffer.
+func (l *Leg) NearSingularity(angles [3]float64) bool {
+	return l.Manipulability(angles) < SingularityThreshold
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+)
+
+func TestToePointAtInvertsJointAngles(t *testing.T) {
+	var l Leg
+	for lp := LegPosition(0); lp <= LegPosition(3); lp++ {
+		l.init(lp)
+		for _, pt := range []Point3D{{X: 0, Y: 0, Z: 0}, {X: 10, Y: -20, Z: -15}, {X: -15, Y: 5, Z: 10}} {
+			l.SetToePoint(pt)
+			bc, cf, ft := l.JointAngles()
+			got := l.ToePointAt([3]float64{bc, cf, ft})
+			if !approxEqualWithin(got, pt, kinematicsEpsilon) {
+				t.Errorf("%v.ToePointAt(JointAngles(%v)) = %v", lp, pt, got)
+			}
+		}
+	}
+}
+
+func TestJacobianMatchesFiniteDifferences(t *testing.T) {
+	var l Leg
+	l.init(FrontRight)
+	angles := [3]float64{0.3, -0.4, 1.7}
+	j := l.Jacobian(angles)
+	const h = 1e-6
+	for k := 0; k < 3; k++ {
+		plus, minus := angles, angles
+		plus[k] += h
+		minus[k] -= h
+		p1, p0 := l.ToePointAt(plus), l.ToePointAt(minus)
+		want := [3]float64{(p1.X - p0.X) / (2 * h), (p1.Y - p0.Y) / (2 * h), (p1.Z - p0.Z) / (2 * h)}
+		for r := 0; r < 3; r++ {
+			if math.Abs(j[r][k]-want[r]) > 1e-4 {
+				t.Errorf("Jacobian()[%d][%d] = %v, want %v", r, k, j[r][k], want[r])
+			}
+		}
+	}
+}
+
+func TestJointVelocitiesRoundTrip(t *testing.T) {
+	var l Leg
+	l.init(BackLeft)
+	angles := [3]float64{-2.0, -0.2, 1.9}
+	toeVel := Point3D{X: 5, Y: -3, Z: 2}
+	got := l.ToeVelocity(angles, l.JointVelocities(angles, toeVel))
+	if math.Abs(got.X-toeVel.X) > 0.01 || math.Abs(got.Y-toeVel.Y) > 0.01 || math.Abs(got.Z-toeVel.Z) > 0.01 {
+		t.Errorf("ToeVelocity(JointVelocities(%v)) = %v", toeVel, got)
+	}
+}
+
+func TestManipulabilityNearStraightKnee(t *testing.T) {
+	var l Leg
+	l.init(FrontRight)
+	tests := []struct {
+		ft       float64
+		singular bool
+	}{
+		{math.Pi / 2, false},
+		{math.Pi * 0.99, true},
+		{math.Pi, true},
+	}
+	for _, tt := range tests {
+		angles := [3]float64{0, 0, tt.ft}
+		if got := l.NearSingularity(angles); got != tt.singular {
+			t.Errorf("NearSingularity(%v) = %v (manipulability %v), want %v", angles, got, l.Manipulability(angles), tt.singular)
+		}
+	}
+}
+
+func TestJointTorquesIsJacobianTranspose(t *testing.T) {
+	var l Leg
+	l.init(FrontRight)
+	angles := [3]float64{0.5, 0, math.Pi / 2}
+	// Pushing straight up on the toe loads the coxa-femur joint with the horizontal reach, and leaves the body-coxa joint alone.
+	got := l.JointTorques(angles, Point3D{Z: 1})
+	if math.Abs(got[0]) > 1e-9 || math.Abs(got[1]-FemurLength) > 1e-9 || math.Abs(got[2]) > 1e-9 {
+		t.Errorf("JointTorques(%v, up) = %v, want (0, %v, 0)", angles, got, FemurLength)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build spider_f32
+// +build spider_f32
+
+package spider
+
+// How far, in distance units or radians, the results of the kinematics can be from exact.
+const kinematicsEpsilon = 1e-3
+
+func legTriangle(dx, dy, dz float64) (float64, float64, float64, float64) {
+	bc, reach, femurReach, ft := legTriangle32(float32(dx), float32(dy), float32(dz))
+	return float64(bc), float64(reach), float64(femurReach), float64(ft)
+}
+
+func radiansToDegrees(rad float64) int16 {
+	return radiansToDegrees32(float32(rad))
+}
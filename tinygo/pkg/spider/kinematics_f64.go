@@ -0,0 +1,33 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !spider_f32
+// +build !spider_f32
+
+package spider
+
+import (
+	"math"
+)
+
+// How far, in distance units or radians, the results of the kinematics can be from exact.
+const kinematicsEpsilon = 1e-6
+
+func legTriangle(dx, dy, dz float64) (float64, float64, float64, float64) {
+	return legTriangle64(dx, dy, dz)
+}
+
+func radiansToDegrees(rad float64) int16 {
+	return int16(math.Round(rad / math.Pi * 180))
+}
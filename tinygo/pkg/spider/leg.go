@@ -34,6 +34,8 @@ const (
 	BodyCoxa Joint = iota
 	CoxaFemur
 	FemurTibia
+	// Only present on Leg4.
+	TibiaTarsus
 )
 
 const (
@@ -42,6 +44,12 @@ const (
 	TibiaLength = 81.0
 )
 
+// Distance from the centre of the body to the body-coxa joints.
+const (
+	BodyHalfWidth  = 33.0
+	BodyHalfLength = 33.0
+)
+
 // Represents a 3D point in space.
 // X is towards the right of the robot.
 // Y is towards the front of the robot.
@@ -51,9 +59,42 @@ type Point3D struct {
 	X, Y, Z float64
 }
 
+func (p Point3D) Add(q Point3D) Point3D {
+	return Point3D{X: p.X + q.X, Y: p.Y + q.Y, Z: p.Z + q.Z}
+}
+
+func (p Point3D) Sub(q Point3D) Point3D {
+	return Point3D{X: p.X - q.X, Y: p.Y - q.Y, Z: p.Z - q.Z}
+}
+
+func (p Point3D) Scale(k float64) Point3D {
+	return Point3D{X: p.X * k, Y: p.Y * k, Z: p.Z * k}
+}
+
+// KneeMode selects which of the two inverse kinematics solutions a leg uses.
+type KneeMode uint8
+
+const (
+	// The femur is above the line from the coxa-femur joint to the toe, like a spider.
+	KneeUp KneeMode = iota
+	// The femur is below the line from the coxa-femur joint to the toe, like a mammal.
+	KneeDown
+	// Whichever solution is closest to the previous joint angles, so the leg never flips through the fully-extended position.
+	KneeClosest
+)
+
 type Leg struct {
 	hipPt Point3D
 	toePt Point3D
+	// Position of the canonical zero toe point in the body frame, whose origin is the centre of the body at hip height.
+	origin   Point3D
+	kneeMode KneeMode
+	// Coxa-femur and femur-tibia angles from the previous solution.
+	prevCF, prevFT float64
+	state          LegState
+	// Joint angles commanded directly, bypassing the IK, if commanded is set.
+	commanded bool
+	cmdAngles [3]float64
 }
 
 func (l *Leg) init(pos LegPosition) {
@@ -61,42 +102,103 @@ func (l *Leg) init(pos LegPosition) {
 	// Therefore the hip joint is displaced by (coxa+femur)/sqrt(2), using Pythagoras' theorem.
 	hipOffset := (CoxaLength + FemurLength) / math.Sqrt(2)
 	var hipX, hipY float64
+	var mountX, mountY float64
 	switch pos {
 	case FrontRight:
 		hipX = -hipOffset
 		hipY = -hipOffset
+		mountX = BodyHalfWidth
+		mountY = BodyHalfLength
 	case FrontLeft:
 		hipX = hipOffset
 		hipY = -hipOffset
+		mountX = -BodyHalfWidth
+		mountY = BodyHalfLength
 	case BackRight:
 		hipX = -hipOffset
 		hipY = hipOffset
+		mountX = BodyHalfWidth
+		mountY = -BodyHalfLength
 	case BackLeft:
 		hipX = hipOffset
 		hipY = hipOffset
+		mountX = -BodyHalfWidth
+		mountY = -BodyHalfLength
 	}
 	l.hipPt = Point3D{
 		X: hipX,
 		Y: hipY,
 		Z: TibiaLength,
 	}
+	l.origin = Point3D{X: mountX, Y: mountY}.Sub(l.hipPt)
+	l.prevCF = 0
+	l.prevFT = math.Pi / 2
+	l.state = Stance
 }
 
 func (l *Leg) SetToePoint(pt Point3D) {
 	l.toePt = pt
+	l.commanded = false
+}
+
+// setJointAngles commands the joint angles directly, without going through the IK.
+// The toe point is updated to match, and the angles stay in force until the next call to SetToePoint.
+func (l *Leg) setJointAngles(angles [3]float64) {
+	l.toePt = l.ToePointAt(angles)
+	l.commanded = true
+	l.cmdAngles = angles
+}
+
+func (l *Leg) SetKneeMode(mode KneeMode) {
+	l.kneeMode = mode
+}
+
+func (l *Leg) KneeMode() KneeMode {
+	return l.kneeMode
 }
 
 func (l *Leg) JointAngles() (float64, float64, float64) {
+	if l.commanded {
+		l.prevCF, l.prevFT = l.cmdAngles[1], l.cmdAngles[2]
+		return l.cmdAngles[0], l.cmdAngles[1], l.cmdAngles[2]
+	}
+	bodyCoxaAngle, reachAngle, femurReachAngle, femurTibiaAngle := legTriangle(l.toePt.X-l.hipPt.X, l.toePt.Y-l.hipPt.Y, l.toePt.Z-l.hipPt.Z)
+
+	// The knee-down solution is the knee-up solution mirrored about the line from the coxa-femur joint to the toe.
+	upCF, upFT := reachAngle+femurReachAngle, femurTibiaAngle
+	downCF, downFT := reachAngle-femurReachAngle, 2*math.Pi-femurTibiaAngle
+	coxaFemurAngle, femurTibiaAngle := upCF, upFT
+	switch l.kneeMode {
+	case KneeDown:
+		coxaFemurAngle, femurTibiaAngle = downCF, downFT
+	case KneeClosest:
+		upDist := math.Abs(upCF-l.prevCF) + math.Abs(upFT-l.prevFT)
+		downDist := math.Abs(downCF-l.prevCF) + math.Abs(downFT-l.prevFT)
+		if downDist < upDist {
+			coxaFemurAngle, femurTibiaAngle = downCF, downFT
+		}
+	}
+	if !math.IsNaN(coxaFemurAngle) && !math.IsNaN(femurTibiaAngle) {
+		l.prevCF, l.prevFT = coxaFemurAngle, femurTibiaAngle
+	}
+
+	return bodyCoxaAngle, coxaFemurAngle, femurTibiaAngle
+}
+
+// legTriangle64 solves the knee-up configuration for a toe which is displaced (dx, dy, dz) from the hip.
+// It returns the body-coxa angle; the angle of the line from the coxa-femur joint to the toe; the angle between that
+// line and the femur; and the femur-tibia angle.
+func legTriangle64(dx, dy, dz float64) (float64, float64, float64, float64) {
 	// Hip angle is measured counter-clockwise from a line projecting out from the side of the spider, so FrontLeft/BackRight angles are negative.
-	bodyCoxaAngle := math.Atan2(l.toePt.Y-l.hipPt.Y, l.toePt.X-l.hipPt.X)
+	bodyCoxaAngle := math.Atan2(dy, dx)
 
 	// Total horizontal distance from hip to toe.
-	horizReach := math.Sqrt((l.toePt.X-l.hipPt.X)*(l.toePt.X-l.hipPt.X) + (l.toePt.Y-l.hipPt.Y)*(l.toePt.Y-l.hipPt.Y))
+	horizReach := math.Sqrt(dx*dx + dy*dy)
 	// Femur+tibia horizontal reach.
 	ftHorizReach := horizReach - CoxaLength
 	// Femur+tibia reach in 3D space.
 	// This gives us a triangle with sides (FemurLength, TibiaLength, ftReach).
-	ftReach := math.Sqrt(ftHorizReach*ftHorizReach + (l.toePt.Z-l.hipPt.Z)*(l.toePt.Z-l.hipPt.Z))
+	ftReach := math.Sqrt(ftHorizReach*ftHorizReach + dz*dz)
 
 	// Solve for angles, using the law of cosines.
 	//   c^2 = a^2 + b^2 - 2*a*b*cos(C)
@@ -114,13 +216,41 @@ func (l *Leg) JointAngles() (float64, float64, float64) {
 	cosDenom = 2.0 * ftReach * FemurLength
 	femurReachAngle := math.Acos(cosNum / cosDenom)
 	// Second, find the angle between horizontal and the imaginary line from the coxa-femur joint down to the  toe.
-	reachAngle := math.Atan2(l.toePt.Z-l.hipPt.Z, ftHorizReach)
-	coxaFemurAngle := femurReachAngle + reachAngle
+	reachAngle := math.Atan2(dz, ftHorizReach)
 
 	// Femur-Tibia angle is measured counter-clockwise from the femur, so it will always be positive, and bigger numbers represent a further reach.
 	cosNum = FemurLength*FemurLength + TibiaLength*TibiaLength - ftReach*ftReach
 	cosDenom = 2.0 * FemurLength * TibiaLength
 	femurTibiaAngle := math.Acos(cosNum / cosDenom)
 
-	return bodyCoxaAngle, coxaFemurAngle, femurTibiaAngle
+	return bodyCoxaAngle, reachAngle, femurReachAngle, femurTibiaAngle
+}
+
+// ToePointAt returns the toe position for the given body-coxa, coxa-femur, and femur-tibia angles.
+// It is the inverse of JointAngles.
+func (l *Leg) ToePointAt(angles [3]float64) Point3D {
+	return l.jointPoints(angles)[3]
+}
+
+// jointPoints returns the positions of the body-coxa, coxa-femur, and femur-tibia joints, and the toe,
+// for the given joint angles.
+func (l *Leg) jointPoints(angles [3]float64) [4]Point3D {
+	bc, cf, ft := angles[0], angles[1], angles[2]
+	tibia := cf + ft - math.Pi
+	cosBC, sinBC := math.Cos(bc), math.Sin(bc)
+	atReach := func(horizReach, z float64) Point3D {
+		return Point3D{
+			X: l.hipPt.X + horizReach*cosBC,
+			Y: l.hipPt.Y + horizReach*sinBC,
+			Z: l.hipPt.Z + z,
+		}
+	}
+	kneeReach := CoxaLength + FemurLength*math.Cos(cf)
+	kneeZ := FemurLength * math.Sin(cf)
+	return [4]Point3D{
+		l.hipPt,
+		atReach(CoxaLength, 0),
+		atReach(kneeReach, kneeZ),
+		atReach(kneeReach+TibiaLength*math.Cos(tibia), kneeZ+TibiaLength*math.Sin(tibia)),
+	}
 }
@@ -0,0 +1,177 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+)
+
+const TarsusLength = 30.0
+
+// Tuning for the numerical solver used by Leg4.
+const (
+	ik4MaxIterations = 100
+	ik4Tolerance     = 0.01
+	ik4Damping       = 1.0
+)
+
+// Leg4 is a leg with an extra tarsus joint at the end of the tibia.
+// The toe target API is the same as Leg, but the joint angles are found numerically,
+// which leaves room for a secondary objective on the angle of the foot.
+type Leg4 struct {
+	hipPt Point3D
+	toePt Point3D
+	// Desired angle of the tarsus, measured counter-clockwise from horizontal, and how hard to try to achieve it.
+	footAngle  float64
+	footWeight float64
+	// Previous solution, used as the starting point for the next solve.
+	angles [4]float64
+}
+
+func (l *Leg4) init(pos LegPosition) {
+	// The canonical zero position is the same as Leg, with the tarsus hanging straight down from the tibia.
+	var leg Leg
+	leg.init(pos)
+	l.hipPt = leg.hipPt
+	l.hipPt.Z = TibiaLength + TarsusLength
+	l.toePt = Point3D{}
+	l.SetFootObjective(-math.Pi/2, 10)
+	l.angles = [4]float64{
+		math.Atan2(-l.hipPt.Y, -l.hipPt.X),
+		0,
+		math.Pi / 2,
+		math.Pi,
+	}
+}
+
+func (l *Leg4) SetToePoint(pt Point3D) {
+	l.toePt = pt
+}
+
+// SetFootObjective sets the secondary objective of the solver.
+// The angle is measured counter-clockwise from horizontal, so -Pi/2 keeps the foot perpendicular to the ground.
+// The weight is roughly the number of distance units that one radian of foot angle error is worth; zero disables the objective.
+func (l *Leg4) SetFootObjective(angle, weight float64) {
+	l.footAngle = angle
+	l.footWeight = weight
+}
+
+// JointAngles returns the body-coxa, coxa-femur, femur-tibia, and tibia-tarsus angles.
+// The first three use the same conventions as Leg. The tibia-tarsus angle is measured counter-clockwise from the tibia,
+// so Pi means the tarsus continues in a straight line.
+// The final result is false if the solver couldn't put the toe on the target, usually because it is out of reach.
+// The angles are then the closest the solver got, and shouldn't be sent to the servos.
+func (l *Leg4) JointAngles() (float64, float64, float64, float64, bool) {
+	dx := l.toePt.X - l.hipPt.X
+	dy := l.toePt.Y - l.hipPt.Y
+	bodyCoxaAngle := math.Atan2(dy, dx)
+
+	// The remaining joints all move in the vertical plane containing the coxa.
+	targetReach := math.Sqrt(dx*dx+dy*dy) - CoxaLength
+	targetZ := l.toePt.Z - l.hipPt.Z
+
+	q := [3]float64{l.angles[1], l.angles[2], l.angles[3]}
+	var e [3]float64
+	for i := 0; ; i++ {
+		reach, z, foot := planarToe4(q)
+		e = [3]float64{
+			targetReach - reach,
+			targetZ - z,
+			l.footWeight * (l.footAngle - foot),
+		}
+		if i == ik4MaxIterations || math.Abs(e[0]) < ik4Tolerance && math.Abs(e[1]) < ik4Tolerance && math.Abs(e[2]) < ik4Tolerance {
+			break
+		}
+
+		dq := dampedLeastSquares(planarJacobian4(q, l.footWeight), e, ik4Damping)
+		for k := 0; k < 3; k++ {
+			q[k] += dq[k]
+		}
+	}
+
+	l.angles = [4]float64{bodyCoxaAngle, q[0], q[1], q[2]}
+	// The foot angle is only a secondary objective, so missing it doesn't count as a failure.
+	ok := math.Abs(e[0]) < ik4Tolerance && math.Abs(e[1]) < ik4Tolerance
+	return l.angles[0], l.angles[1], l.angles[2], l.angles[3], ok
+}
+
+// planarToe4 returns the horizontal reach and height of the toe relative to the coxa-femur joint, and the foot angle,
+// for the given coxa-femur, femur-tibia, and tibia-tarsus angles.
+func planarToe4(q [3]float64) (float64, float64, float64) {
+	femur := q[0]
+	tibia := femur + q[1] - math.Pi
+	tarsus := tibia + q[2] - math.Pi
+	reach := FemurLength*math.Cos(femur) + TibiaLength*math.Cos(tibia) + TarsusLength*math.Cos(tarsus)
+	z := FemurLength*math.Sin(femur) + TibiaLength*math.Sin(tibia) + TarsusLength*math.Sin(tarsus)
+	return reach, z, tarsus
+}
+
+// planarJacobian4 returns the partial derivatives of planarToe4, with the foot angle row scaled by the given weight.
+func planarJacobian4(q [3]float64, footWeight float64) [3][3]float64 {
+	femur := q[0]
+	tibia := femur + q[1] - math.Pi
+	tarsus := tibia + q[2] - math.Pi
+	// Each joint moves everything beyond it, so the columns are cumulative from the toe.
+	dr3 := -TarsusLength * math.Sin(tarsus)
+	dz3 := TarsusLength * math.Cos(tarsus)
+	dr2 := dr3 - TibiaLength*math.Sin(tibia)
+	dz2 := dz3 + TibiaLength*math.Cos(tibia)
+	dr1 := dr2 - FemurLength*math.Sin(femur)
+	dz1 := dz2 + FemurLength*math.Cos(femur)
+	return [3][3]float64{
+		{dr1, dr2, dr3},
+		{dz1, dz2, dz3},
+		{footWeight, footWeight, footWeight},
+	}
+}
+
+// dampedLeastSquares returns dq = J^T * (J*J^T + lambda^2*I)^-1 * e, which stays well-behaved near singularities.
+func dampedLeastSquares(j [3][3]float64, e [3]float64, lambda float64) [3]float64 {
+	var jjt [3][3]float64
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			for k := 0; k < 3; k++ {
+				jjt[r][c] += j[r][k] * j[c][k]
+			}
+		}
+		jjt[r][r] += lambda * lambda
+	}
+	f := solve3(jjt, e)
+	var dq [3]float64
+	for k := 0; k < 3; k++ {
+		dq[k] = j[0][k]*f[0] + j[1][k]*f[1] + j[2][k]*f[2]
+	}
+	return dq
+}
+
+// solve3 solves a*x = b using Cramer's rule.
+func solve3(a [3][3]float64, b [3]float64) [3]float64 {
+	det := det3(a)
+	var x [3]float64
+	for c := 0; c < 3; c++ {
+		m := a
+		for r := 0; r < 3; r++ {
+			m[r][c] = b[r]
+		}
+		x[c] = det3(m) / det
+	}
+	return x
+}
+
+func det3(a [3][3]float64) float64 {
+	return a[0][0]*(a[1][1]*a[2][2]-a[1][2]*a[2][1]) -
+		a[0][1]*(a[1][0]*a[2][2]-a[1][2]*a[2][0]) +
+		a[0][2]*(a[1][0]*a[2][1]-a[1][1]*a[2][0])
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLeg4JointAnglesAtNullPoint(t *testing.T) {
+	var l Leg4
+	for lp := LegPosition(0); lp <= LegPosition(3); lp++ {
+		l.init(lp)
+		l.SetToePoint(Point3D{})
+		_, cf, ft, tt, _ := l.JointAngles()
+		if got, want := approxRadToDeg(cf), 0; got != want {
+			t.Errorf("%v.JointAngles() returned (_, %v, _, _), expected %v", lp, got, want)
+		}
+		if got, want := approxRadToDeg(ft), 90; got != want {
+			t.Errorf("%v.JointAngles() returned (_, _, %v, _), expected %v", lp, got, want)
+		}
+		if got, want := approxRadToDeg(tt), 180; got != want {
+			t.Errorf("%v.JointAngles() returned (_, _, _, %v), expected %v", lp, got, want)
+		}
+	}
+}
+
+func TestLeg4JointAnglesReachTarget(t *testing.T) {
+	var l Leg4
+	targets := []Point3D{
+		{X: 0, Y: 0, Z: 0},
+		{X: 10, Y: -15, Z: 5},
+		{X: -20, Y: 20, Z: -10},
+		{X: 15, Y: 15, Z: 20},
+	}
+	for lp := LegPosition(0); lp <= LegPosition(3); lp++ {
+		l.init(lp)
+		for _, pt := range targets {
+			l.SetToePoint(pt)
+			bc, cf, ft, tt, ok := l.JointAngles()
+			if !ok {
+				t.Errorf("%v.JointAngles(%v) reported the target as unreachable", lp, pt)
+			}
+			reach, z, foot := planarToe4([3]float64{cf, ft, tt})
+			reach += CoxaLength
+			got := Point3D{
+				X: l.hipPt.X + reach*math.Cos(bc),
+				Y: l.hipPt.Y + reach*math.Sin(bc),
+				Z: l.hipPt.Z + z,
+			}
+			if math.Abs(got.X-pt.X) > 0.1 || math.Abs(got.Y-pt.Y) > 0.1 || math.Abs(got.Z-pt.Z) > 0.1 {
+				t.Errorf("%v.JointAngles(%v) reaches %v", lp, pt, got)
+			}
+			if got, want := approxRadToDeg(foot), -90; got != want {
+				t.Errorf("%v.JointAngles(%v) has foot angle %v, expected %v", lp, pt, got, want)
+			}
+		}
+	}
+}
+
+func TestLeg4FootObjectiveDisabled(t *testing.T) {
+	var l Leg4
+	l.init(FrontRight)
+	l.SetFootObjective(0, 0)
+	pt := Point3D{X: 10, Y: 10, Z: -10}
+	l.SetToePoint(pt)
+	_, cf, ft, tt, _ := l.JointAngles()
+	reach, z, _ := planarToe4([3]float64{cf, ft, tt})
+	wantReach := math.Sqrt((pt.X-l.hipPt.X)*(pt.X-l.hipPt.X)+(pt.Y-l.hipPt.Y)*(pt.Y-l.hipPt.Y)) - CoxaLength
+	if math.Abs(reach-wantReach) > 0.1 || math.Abs(z-(pt.Z-l.hipPt.Z)) > 0.1 {
+		t.Errorf("JointAngles(%v) reaches (%v, %v), expected (%v, %v)", pt, reach, z, wantReach, pt.Z-l.hipPt.Z)
+	}
+}
+
+func TestLeg4JointAnglesUnreachable(t *testing.T) {
+	var l Leg4
+	l.init(FrontRight)
+	for _, pt := range []Point3D{{Z: -500}, {X: 300, Y: 300}} {
+		l.SetToePoint(pt)
+		if _, _, _, _, ok := l.JointAngles(); ok {
+			t.Errorf("JointAngles(%v) reported an unreachable target as solved", pt)
+		}
+	}
+	// The solver recovers once the target is back in reach.
+	l.SetToePoint(Point3D{})
+	if _, _, _, _, ok := l.JointAngles(); !ok {
+		t.Error("JointAngles() failed to solve the null point after an unreachable target")
+	}
+}
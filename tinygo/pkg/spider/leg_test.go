@@ -129,3 +129,55 @@ func TestJointAnglesAheadOrBehindBody(t *testing.T) {
 		}
 	}
 }
+
+// planarToe returns the horizontal reach and height of the toe relative to the coxa-femur joint.
+func planarToe(cf, ft float64) (float64, float64) {
+	tibia := cf + ft - math.Pi
+	return FemurLength*math.Cos(cf) + TibiaLength*math.Cos(tibia), FemurLength*math.Sin(cf) + TibiaLength*math.Sin(tibia)
+}
+
+func TestJointAnglesKneeModes(t *testing.T) {
+	var l Leg
+	l.init(FrontRight)
+	toePt := Point3D{X: 10, Y: 5, Z: -15}
+	l.SetToePoint(toePt)
+
+	l.SetKneeMode(KneeUp)
+	_, upCF, upFT := l.JointAngles()
+	l.SetKneeMode(KneeDown)
+	_, downCF, downFT := l.JointAngles()
+
+	if downCF >= upCF {
+		t.Errorf("KneeDown coxa-femur angle %v, expected less than KneeUp angle %v", downCF, upCF)
+	}
+	upReach, upZ := planarToe(upCF, upFT)
+	downReach, downZ := planarToe(downCF, downFT)
+	if math.Abs(upReach-downReach) > kinematicsEpsilon || math.Abs(upZ-downZ) > kinematicsEpsilon {
+		t.Errorf("KneeUp reaches (%v, %v) but KneeDown reaches (%v, %v)", upReach, upZ, downReach, downZ)
+	}
+}
+
+func TestJointAnglesKneeClosest(t *testing.T) {
+	startPt := Point3D{X: 0, Y: 0, Z: -10}
+	nextPt := Point3D{X: 1, Y: 1, Z: -11}
+	for _, mode := range []KneeMode{KneeUp, KneeDown} {
+		var want Leg
+		want.init(FrontRight)
+		want.SetKneeMode(mode)
+		want.SetToePoint(nextPt)
+		_, wantCF, wantFT := want.JointAngles()
+
+		// Once the leg is on a branch, small moves should keep it there.
+		var l Leg
+		l.init(FrontRight)
+		l.SetKneeMode(mode)
+		l.SetToePoint(startPt)
+		l.JointAngles()
+		l.SetKneeMode(KneeClosest)
+		l.SetToePoint(nextPt)
+		_, gotCF, gotFT := l.JointAngles()
+		if gotCF != wantCF || gotFT != wantFT {
+			t.Errorf("KneeClosest after %v returned (_, %v, %v), expected (_, %v, %v)", mode, gotCF, gotFT, wantCF, wantFT)
+		}
+	}
+}
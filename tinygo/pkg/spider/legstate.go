@@ -0,0 +1,108 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"fmt"
+)
+
+// LegState records what a leg is supposed to be doing, and in particular whether its foot bears weight.
+type LegState int
+
+const (
+	// Folded up for power off, with the body resting on the ground.
+	Parked LegState = iota
+	// On the ground, bearing weight.
+	Stance
+	// In the air, being moved by the gait.
+	Swing
+	// Held in the air by a motion primitive or LiftLeg.
+	Lifted
+	// Not under control, e.g. after a safe stop.
+	Faulted
+)
+
+func (st LegState) String() string {
+	switch st {
+	case Parked:
+		return "parked"
+	case Stance:
+		return "stance"
+	case Swing:
+		return "swing"
+	case Lifted:
+		return "lifted"
+	case Faulted:
+		return "faulted"
+	}
+	return fmt.Sprintf("LegState(%d)", int(st))
+}
+
+// legTransitions lists the states each state may move to, apart from itself.
+// Every state may become faulted, but a faulted leg has to stand or park before it can do anything else.
+var legTransitions = map[LegState][]LegState{
+	Parked:  {Stance, Faulted},
+	Stance:  {Swing, Lifted, Parked, Faulted},
+	Swing:   {Stance, Faulted},
+	Lifted:  {Stance, Parked, Faulted},
+	Faulted: {Stance, Parked},
+}
+
+// CanTransition reports whether a leg may move from one state to another.
+func CanTransition(from, to LegState) bool {
+	if from == to {
+		return true
+	}
+	for _, st := range legTransitions[from] {
+		if st == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Leg) State() LegState {
+	return l.state
+}
+
+// setState changes the leg's state, returning false and leaving it alone if the transition isn't allowed.
+func (l *Leg) setState(st LegState) bool {
+	if !CanTransition(l.state, st) {
+		return false
+	}
+	l.state = st
+	return true
+}
+
+func (s *Spider) LegState(leg LegPosition) LegState {
+	return s.legs[leg].state
+}
+
+// SetLegState changes the state of a leg, e.g. when contact sensing finds that a foot has hit the ground early.
+func (s *Spider) SetLegState(leg LegPosition, st LegState) error {
+	if !s.legs[leg].setState(st) {
+		return fmt.Errorf("spider: leg %d can't go from %v to %v", leg, s.legs[leg].state, st)
+	}
+	return nil
+}
+
+// stance reports which feet are on the ground, bearing weight.
+func (s *Spider) stance() [4]bool {
+	var stance [4]bool
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		stance[leg] = s.legs[leg].state == Stance
+	}
+	return stance
+}
@@ -0,0 +1,183 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to LegState
+		want     bool
+	}{
+		{Stance, Stance, true},
+		{Stance, Swing, true},
+		{Swing, Stance, true},
+		{Stance, Lifted, true},
+		{Lifted, Stance, true},
+		{Stance, Parked, true},
+		{Parked, Stance, true},
+		{Swing, Faulted, true},
+		{Faulted, Stance, true},
+		{Parked, Swing, false},
+		{Parked, Lifted, false},
+		{Swing, Lifted, false},
+		{Lifted, Swing, false},
+		{Faulted, Swing, false},
+		{Faulted, Lifted, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%v, %v) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestSetLegState(t *testing.T) {
+	s := newTestSpider()
+	if got := s.LegState(FrontLeft); got != Stance {
+		t.Fatalf("LegState() of a new Spider = %v, want stance", got)
+	}
+	if err := s.SetLegState(FrontLeft, Parked); err != nil {
+		t.Fatalf("SetLegState(stance -> parked) returned %v", err)
+	}
+	if err := s.SetLegState(FrontLeft, Swing); err == nil {
+		t.Error("SetLegState(parked -> swing) succeeded, want an error")
+	}
+	if got := s.LegState(FrontLeft); got != Parked {
+		t.Errorf("LegState() after a rejected transition = %v, want parked", got)
+	}
+	if s.stance()[FrontLeft] {
+		t.Error("a parked leg counts as bearing weight")
+	}
+}
+
+func TestWalkUsesLegStates(t *testing.T) {
+	s := newTestSpider()
+	// Lean away from the back left leg, so that it can be lifted.
+	s.SetBodyPose(Point3D{X: 10, Y: 10}, 0, 0, 0)
+	if err := s.LiftLeg(BackLeft, 20); err != nil {
+		t.Fatal(err)
+	}
+	lifted := s.ToePoint(BackLeft)
+	s.SetVelocity(20, 0, 0)
+	swung := [4]bool{}
+	// Stop part way through the front right leg's swing.
+	for i := 0; i < 270; i++ {
+		s.Update(10 * time.Millisecond)
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if s.LegState(leg) == Swing {
+				swung[leg] = true
+			}
+		}
+	}
+	if want := [4]bool{true, true, true, false}; swung != want {
+		t.Errorf("legs which swung = %v, want %v", swung, want)
+	}
+	if got := s.ToePoint(BackLeft); got != lifted {
+		t.Errorf("lifted foot moved from %v to %v while walking", lifted, got)
+	}
+
+	// Stopping for a move puts the foot in the air down before the move, and it only counts as in stance once it's
+	// on the ground.
+	if got := s.LegState(FrontRight); got != Swing || s.ToePoint(FrontRight).Z <= 0 {
+		t.Fatalf("when walking stopped, the front right leg is in state %v at %v, want swinging", got, s.ToePoint(FrontRight))
+	}
+	s.ShiftBody(5, 0, time.Second)
+	for s.Moving() {
+		s.Update(10 * time.Millisecond)
+		if s.LegState(FrontRight) == Stance && s.ToePoint(FrontRight).Z != 0 {
+			t.Fatalf("the front right leg is in stance with its foot at %v", s.ToePoint(FrontRight))
+		}
+	}
+	if got := s.LegState(FrontRight); got != Stance {
+		t.Errorf("LegState(FrontRight) after the move = %v, want stance", got)
+	}
+	if got := s.ToePoint(FrontRight).Z; got != 0 {
+		t.Errorf("after the move, the front right foot is at height %v, want 0", got)
+	}
+	if got := s.BodyPose().Translation.X; math.Abs(got-15) > 1e-9 {
+		t.Errorf("after the move, the body is at X = %v, want 15", got)
+	}
+}
+
+func TestSafeStopFaultsLegs(t *testing.T) {
+	s, _ := newFlakySpider(FaultPolicy{TripAfter: 1}, 1)
+	s.SendCommandsToServos()
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if got := s.LegState(leg); got != Faulted {
+			t.Errorf("LegState(%d) after a safe stop = %v, want faulted", leg, got)
+		}
+	}
+	s.ClearFault()
+	if got := s.stance(); got != [4]bool{true, true, true, true} {
+		t.Errorf("stance() after ClearFault() = %v, want all feet down", got)
+	}
+}
+
+func TestParkAndStandUpStates(t *testing.T) {
+	s := newTestSpider()
+	s.Park(time.Second)
+	runUntilIdle(t, s)
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if got := s.LegState(leg); got != Parked {
+			t.Errorf("LegState(%d) after Park() = %v, want parked", leg, got)
+		}
+	}
+	s.StandUp(TibiaLength, time.Second)
+	s.Update(10 * time.Millisecond)
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if got := s.LegState(leg); got != Stance {
+			t.Errorf("LegState(%d) after StandUp() = %v, want stance", leg, got)
+		}
+	}
+}
+
+func TestStandUpLowersLiftedLegs(t *testing.T) {
+	s := newTestSpider()
+	s.ShiftWeightOffLeg(FrontLeft, 10, 200*time.Millisecond)
+	s.LegUp(FrontLeft, 20, 200*time.Millisecond)
+	s.StandUp(70, time.Second)
+	runUntilIdle(t, s)
+	if err := s.QueueError(); err != nil {
+		t.Fatalf("QueueError() = %v", err)
+	}
+	if got := s.LegState(FrontLeft); got != Stance {
+		t.Errorf("LegState(FrontLeft) after StandUp() = %v, want stance", got)
+	}
+	if got, want := s.ToePoint(FrontLeft), (Point3D{Z: TibiaLength - 70}); !approxEqual(got, want) {
+		t.Errorf("after StandUp, the front left foot is at %v, want %v", got, want)
+	}
+}
+
+func TestPrimitivesRespectLegStates(t *testing.T) {
+	s := newTestSpider()
+	if err := s.SetLegState(FrontRight, Parked); err != nil {
+		t.Fatal(err)
+	}
+	start := s.ToePoint(FrontRight)
+	s.LegUp(FrontRight, 20, time.Second)
+	s.LegDown(FrontRight, time.Second)
+	runUntilIdle(t, s)
+	if got := s.ToePoint(FrontRight); got != start {
+		t.Errorf("LegUp() moved a parked foot from %v to %v", start, got)
+	}
+	if got := s.LegState(FrontRight); got != Parked {
+		t.Errorf("LegState() after LegUp() on a parked leg = %v, want parked", got)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"time"
+)
+
+// Clock is the source of time for a Loop, so that it can be tested on the host.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// SystemClock is the real time clock.
+var SystemClock Clock = systemClock{}
+
+// LoopStats describes the timing of a Loop so far.
+type LoopStats struct {
+	Ticks int
+	// Ticks that took so long that the next deadline was missed.
+	Overruns int
+	// Longest time spent in a single tick.
+	WorstLoopTime time.Duration
+	// How late a tick started compared to its deadline: the largest, and the average.
+	MaxJitter  time.Duration
+	MeanJitter time.Duration
+}
+
+// Loop runs a function at a fixed rate. The deadlines are absolute, so time spent in the function
+// doesn't make the rate drift. If a tick overruns, the missed deadlines are dropped rather than run back to back.
+type Loop struct {
+	clock       Clock
+	period      time.Duration
+	stats       LoopStats
+	totalJitter time.Duration
+}
+
+func NewLoop(clock Clock, period time.Duration) *Loop {
+	return &Loop{
+		clock:  clock,
+		period: period,
+	}
+}
+
+// Run calls tick once per period until it returns false.
+// The first tick is one period after Run is called. Each tick is passed the actual time since the previous one.
+func (l *Loop) Run(tick func(dt time.Duration) bool) {
+	last := l.clock.Now()
+	deadline := last
+	for {
+		deadline = deadline.Add(l.period)
+		if wait := deadline.Sub(l.clock.Now()); wait > 0 {
+			l.clock.Sleep(wait)
+		}
+
+		start := l.clock.Now()
+		dt := start.Sub(last)
+		last = start
+		more := tick(dt)
+		end := l.clock.Now()
+
+		l.record(start.Sub(deadline), end.Sub(start))
+		if end.After(deadline.Add(l.period)) {
+			l.stats.Overruns++
+			deadline = end.Add(-l.period)
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+func (l *Loop) record(jitter, loopTime time.Duration) {
+	l.stats.Ticks++
+	if jitter > l.stats.MaxJitter {
+		l.stats.MaxJitter = jitter
+	}
+	l.totalJitter += jitter
+	l.stats.MeanJitter = l.totalJitter / time.Duration(l.stats.Ticks)
+	if loopTime > l.stats.WorstLoopTime {
+		l.stats.WorstLoopTime = loopTime
+	}
+}
+
+func (l *Loop) Stats() LoopStats {
+	return l.stats
+}
+
+// ResetStats clears the statistics, e.g. after a slow startup.
+func (l *Loop) ResetStats() {
+	l.stats = LoopStats{}
+	l.totalJitter = 0
+}
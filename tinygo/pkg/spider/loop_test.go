@@ -0,0 +1,116 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when something sleeps or does work.
+type fakeClock struct {
+	now time.Time
+	// Extra delay added to every sleep, to simulate a late wakeup.
+	oversleep time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d + c.oversleep)
+}
+
+func (c *fakeClock) work(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestLoopKeepsFixedRate(t *testing.T) {
+	clock := &fakeClock{}
+	start := clock.Now()
+	l := NewLoop(clock, 10*time.Millisecond)
+	var dts []time.Duration
+	l.Run(func(dt time.Duration) bool {
+		dts = append(dts, dt)
+		// Variable amounts of work must not change the rate.
+		clock.work(time.Duration(len(dts)%3) * time.Millisecond)
+		return len(dts) < 100
+	})
+	for i, dt := range dts {
+		if dt != 10*time.Millisecond {
+			t.Errorf("tick %d: dt = %v, want 10ms", i, dt)
+		}
+	}
+	if got, want := clock.Now().Sub(start), time.Second+time.Millisecond; got != want {
+		t.Errorf("100 ticks took %v, want %v", got, want)
+	}
+	stats := l.Stats()
+	if stats.Ticks != 100 || stats.Overruns != 0 || stats.WorstLoopTime != 2*time.Millisecond || stats.MaxJitter != 0 {
+		t.Errorf("Stats() = %+v, want 100 ticks, no overruns or jitter, worst loop time 2ms", stats)
+	}
+}
+
+func TestLoopJitter(t *testing.T) {
+	clock := &fakeClock{oversleep: time.Millisecond}
+	l := NewLoop(clock, 10*time.Millisecond)
+	var dts []time.Duration
+	l.Run(func(dt time.Duration) bool {
+		dts = append(dts, dt)
+		return len(dts) < 10
+	})
+	// Waking up late delays the first tick, but not the deadlines after it.
+	for i, dt := range dts {
+		want := 10 * time.Millisecond
+		if i == 0 {
+			want = 11 * time.Millisecond
+		}
+		if dt != want {
+			t.Errorf("tick %d: dt = %v, want %v", i, dt, want)
+		}
+	}
+	stats := l.Stats()
+	if stats.MaxJitter != time.Millisecond || stats.MeanJitter != time.Millisecond {
+		t.Errorf("Stats() = %+v, want 1ms max and mean jitter", stats)
+	}
+}
+
+func TestLoopOverrun(t *testing.T) {
+	clock := &fakeClock{}
+	l := NewLoop(clock, 10*time.Millisecond)
+	var dts []time.Duration
+	l.Run(func(dt time.Duration) bool {
+		dts = append(dts, dt)
+		if len(dts) == 2 {
+			clock.work(25 * time.Millisecond)
+		}
+		return len(dts) < 5
+	})
+	// The slow tick is followed by one immediate catch-up tick, then the normal rate resumes.
+	want := []time.Duration{10, 10, 25, 10, 10}
+	for i := range want {
+		if dts[i] != want[i]*time.Millisecond {
+			t.Errorf("tick %d: dt = %v, want %v", i, dts[i], want[i]*time.Millisecond)
+		}
+	}
+	stats := l.Stats()
+	if stats.Overruns != 1 || stats.WorstLoopTime != 25*time.Millisecond {
+		t.Errorf("Stats() = %+v, want 1 overrun and worst loop time 25ms", stats)
+	}
+	l.ResetStats()
+	if l.Stats() != (LoopStats{}) {
+		t.Errorf("Stats() after ResetStats() = %+v", l.Stats())
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"fmt"
+	"time"
+)
+
+// Interpolation selects how a move gets from one pose to the next.
+type Interpolation int
+
+const (
+	// Cartesian moves each foot, and the body, in a straight line.
+	Cartesian Interpolation = iota
+	// JointSpace moves each joint at a steady rate, which gives the smoothest servo motion, but the feet follow
+	// curved paths. The IK is only used at the ends of the move.
+	JointSpace
+)
+
+func (i Interpolation) String() string {
+	switch i {
+	case Cartesian:
+		return "cartesian"
+	case JointSpace:
+		return "joint-space"
+	}
+	return fmt.Sprintf("Interpolation(%d)", int(i))
+}
+
+// Number of points along a Cartesian move which Move checks are reachable, not counting the start.
+const cartesianSamples = 20
+
+// Pose is a complete target for the spider: where each foot is planted, and where the body is relative to them.
+type Pose struct {
+	Feet [4]Point3D
+	Body BodyPose
+}
+
+// Easing maps the fraction of a move's time elapsed, in [0, 1], to the fraction of the distance covered.
+type Easing func(u float64) float64
+
+func Linear(u float64) float64 {
+	return u
+}
+
+func EaseIn(u float64) float64 {
+	return u * u
+}
+
+func EaseOut(u float64) float64 {
+	return u * (2 - u)
+}
+
+// EaseInOut starts and ends with zero velocity.
+func EaseInOut(u float64) float64 {
+	return smoothstep(u)
+}
+
+// queuedMove is a move which hasn't started yet.
+type queuedMove struct {
+	target   func(s *Spider, p Pose) Pose
+	duration time.Duration
+	easing   Easing
+}
+
+// move is a timed transition from one pose to another.
+type move struct {
+	from, to Pose
+	// Joint angles at each end, for joint-space moves.
+	fromAngles, toAngles [4][3]float64
+	jointSpace           bool
+	// If set, the move starts from wherever this earlier move is, rather than from a fixed pose.
+	blendFrom         *move
+	duration, elapsed time.Duration
+	easing            Easing
+	// Legs in swing which the move puts down, and which go into stance when it finishes.
+	lands [4]bool
+	// The move to start, from wherever this one finishes, once it is done.
+	then *move
+}
+
+// Pose returns the spider's current pose.
+func (s *Spider) Pose() Pose {
+	return Pose{Feet: s.feet, Body: s.body}
+}
+
+// MoveTo moves smoothly from the current pose to the target over the given duration, with each foot following
+// a straight line. The move happens on subsequent calls to Update.
+// If a move is already in progress it is abandoned, along with any queued moves, and the new move starts from
+// wherever the spider is.
+func (s *Spider) MoveTo(target Pose, duration time.Duration, easing Easing) {
+	s.queue = nil
+	s.startMove(&move{from: s.Pose(), to: target, duration: duration, easing: easing})
+}
+
+// MoveJointsTo is like MoveTo, but interpolates the joint angles rather than the toe points.
+// This gives the smoothest servo motion, but the feet follow curved paths.
+func (s *Spider) MoveJointsTo(target Pose, duration time.Duration, easing Easing) {
+	s.queue = nil
+	s.startMove(s.jointMove(s.Pose(), target, duration, easing))
+}
+
+// Move is like MoveTo or MoveJointsTo, depending on the interpolation, but first checks that the move is possible.
+// A joint-space move only needs both ends to be reachable, but for a Cartesian move the check is repeated at points
+// along the way, since a straight line between two reachable poses can leave the workspace.
+// If the check fails, Move returns an error and the current move carries on.
+func (s *Spider) Move(target Pose, duration time.Duration, easing Easing, interp Interpolation) error {
+	from := s.Pose()
+	switch interp {
+	case Cartesian:
+		for i := 1; i <= cartesianSamples; i++ {
+			u := float64(i) / cartesianSamples
+			if err := s.CheckReachable(lerpPose(from, target, u)); err != nil {
+				return fmt.Errorf("%w, %.0f%% of the way through the move", err, u*100)
+			}
+		}
+		s.MoveTo(target, duration, easing)
+	case JointSpace:
+		if err := s.CheckReachable(target); err != nil {
+			return err
+		}
+		s.MoveJointsTo(target, duration, easing)
+	default:
+		return fmt.Errorf("spider: unknown interpolation %v", interp)
+	}
+	return nil
+}
+
+// BlendTo is like MoveTo, but if a move is already in progress it keeps going, and the spider gradually
+// transitions from following it to heading for the new target. This avoids a sudden change of direction.
+func (s *Spider) BlendTo(target Pose, duration time.Duration, easing Easing) {
+	s.queue = nil
+	m := &move{from: s.Pose(), to: target, duration: duration, easing: easing}
+	if s.move != nil && !s.move.jointSpace && !s.move.done() {
+		m.blendFrom = s.move
+	}
+	s.startMove(m)
+}
+
+// Moving reports whether a move is in progress, or queued.
+func (s *Spider) Moving() bool {
+	return s.move != nil || len(s.queue) > 0
+}
+
+// CancelMove stops the current move and any queued moves, leaving the spider where it is.
+func (s *Spider) CancelMove() {
+	s.move = nil
+	s.queue = nil
+}
+
+// queueMove adds a Cartesian move to the queue. The target is worked out from the pose at the time the move starts,
+// so queued moves can be relative to wherever the previous one finished.
+func (s *Spider) queueMove(target func(s *Spider, p Pose) Pose, duration time.Duration, easing Easing) {
+	if !s.Moving() {
+		s.queueErr = nil
+	}
+	s.queue = append(s.queue, queuedMove{target: target, duration: duration, easing: easing})
+}
+
+// startQueuedMove starts the next queued move, if there is one.
+func (s *Spider) startQueuedMove() {
+	if len(s.queue) == 0 {
+		return
+	}
+	// Queued targets are worked out from the pose the move starts at, so any feet the gait left in the air are put
+	// down first.
+	if land := s.landingMove(); land != nil {
+		s.stopWalking()
+		s.ResetGait()
+		s.move = land
+		return
+	}
+	q := s.queue[0]
+	s.queue = s.queue[1:]
+	from := s.Pose()
+	s.startMove(&move{from: from, to: q.target(s, from), duration: q.duration, easing: q.easing})
+}
+
+// jointMove returns a joint-space move between two poses.
+func (s *Spider) jointMove(from, to Pose, duration time.Duration, easing Easing) *move {
+	return &move{
+		from:       from,
+		to:         to,
+		fromAngles: s.poseAngles(from),
+		toAngles:   s.poseAngles(to),
+		jointSpace: true,
+		duration:   duration,
+		easing:     easing,
+	}
+}
+
+func (s *Spider) startMove(m *move) {
+	if m.easing == nil {
+		m.easing = Linear
+	}
+	// Moves, walking and applied gaits all own the feet, so the most recent command wins.
+	s.stopWalking()
+	s.ResetGait()
+	// Any feet the gait left in the air are put down first, and the move starts from wherever they land.
+	if land := s.landingMove(); land != nil {
+		m.blendFrom = nil
+		land.then = m
+		m = land
+	}
+	s.move = m
+}
+
+func (s *Spider) updateMove(dt time.Duration) {
+	m := s.move
+	m.advance(dt)
+	if !m.jointSpace {
+		p := m.pose()
+		s.feet = p.Feet
+		s.body = p.Body
+		s.updateLegs()
+	} else {
+		s.updateJointMove(m)
+	}
+	if m.done() {
+		s.finishMove(m)
+	}
+}
+
+// finishMove ends a move, counting the feet it put down as being in stance, and starts the move after it, if any.
+func (s *Spider) finishMove(m *move) {
+	s.move = nil
+	for leg, land := range m.lands {
+		if land {
+			s.legs[leg].setState(Stance)
+		}
+	}
+	if next := m.then; next != nil {
+		next.from = s.Pose()
+		if next.jointSpace {
+			next.fromAngles = s.poseAngles(next.from)
+		}
+		s.move = next
+	}
+}
+
+// updateJointMove moves the joints to the current point in a joint-space move.
+func (s *Spider) updateJointMove(m *move) {
+
+	// The joint angles are sent to the legs directly. The feet are only worked out so that Pose stays up to date.
+	u := m.progress()
+	s.body = lerpBodyPose(m.from.Body, m.to.Body, u)
+	body := s.body.transform()
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		var angles [3]float64
+		for j := range angles {
+			angles[j] = m.fromAngles[leg][j] + (m.toAngles[leg][j]-m.fromAngles[leg][j])*u
+		}
+		l := &s.legs[leg]
+		l.setJointAngles(angles)
+		s.feet[leg] = body.toWorld(l.toePt.Add(l.origin)).Sub(l.origin)
+	}
+}
+
+// poseAngles returns the joint angles needed for each leg to reach the given pose.
+func (s *Spider) poseAngles(p Pose) [4][3]float64 {
+	var angles [4][3]float64
+	body := p.Body.transform()
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		angles[leg] = s.legAngles(leg, p.Feet[leg], &body)
+	}
+	return angles
+}
+
+// legAngles returns the joint angles needed for a leg to reach the given world frame toe point, with the body at
+// the given pose.
+func (s *Spider) legAngles(leg LegPosition, foot Point3D, body *bodyTransform) [3]float64 {
+	// Work on a copy, so that the leg's own solver state isn't disturbed.
+	l := s.legs[leg]
+	l.SetToePoint(body.toBody(foot.Add(l.origin)).Sub(l.origin))
+	bc, cf, ft := l.JointAngles()
+	return [3]float64{bc, cf, ft}
+}
+
+func (m *move) advance(dt time.Duration) {
+	m.elapsed += dt
+	if m.elapsed > m.duration {
+		m.elapsed = m.duration
+	}
+	if m.blendFrom != nil {
+		m.blendFrom.advance(dt)
+		// Once the earlier move has finished it stays put, so there's no need to keep following it.
+		if m.blendFrom.done() {
+			m.from = m.blendFrom.pose()
+			m.blendFrom = nil
+		}
+	}
+}
+
+func (m *move) done() bool {
+	return m.elapsed >= m.duration
+}
+
+// progress returns the eased fraction of the move completed.
+func (m *move) progress() float64 {
+	if m.duration <= 0 {
+		return 1
+	}
+	return m.easing(float64(m.elapsed) / float64(m.duration))
+}
+
+// pose returns the Cartesian pose at the current point in the move.
+func (m *move) pose() Pose {
+	from := m.from
+	if m.blendFrom != nil {
+		from = m.blendFrom.pose()
+	}
+	return lerpPose(from, m.to, m.progress())
+}
+
+func lerpPose(a, b Pose, u float64) Pose {
+	var p Pose
+	for leg := range p.Feet {
+		p.Feet[leg] = a.Feet[leg].Add(b.Feet[leg].Sub(a.Feet[leg]).Scale(u))
+	}
+	p.Body = lerpBodyPose(a.Body, b.Body, u)
+	return p
+}
+
+func lerpBodyPose(a, b BodyPose, u float64) BodyPose {
+	return BodyPose{
+		Translation: a.Translation.Add(b.Translation.Sub(a.Translation).Scale(u)),
+		Roll:        a.Roll + (b.Roll-a.Roll)*u,
+		Pitch:       a.Pitch + (b.Pitch-a.Pitch)*u,
+		Yaw:         a.Yaw + (b.Yaw-a.Yaw)*u,
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func testPose() Pose {
+	return Pose{
+		Feet: [4]Point3D{
+			{X: 10, Y: 10, Z: -10},
+			{X: -10, Y: 10, Z: -10},
+			{X: 10, Y: -10, Z: -10},
+			{X: -10, Y: -10, Z: -10},
+		},
+		Body: BodyPose{Translation: Point3D{Z: 5}, Pitch: 0.1},
+	}
+}
+
+func posesEqual(a, b Pose) bool {
+	for leg := range a.Feet {
+		// Joint-space moves end wherever the kinematics put the feet.
+		if !approxEqualWithin(a.Feet[leg], b.Feet[leg], kinematicsEpsilon) {
+			return false
+		}
+	}
+	return approxEqualWithin(a.Body.Translation, b.Body.Translation, 1e-6) &&
+		math.Abs(a.Body.Roll-b.Body.Roll) < 1e-6 &&
+		math.Abs(a.Body.Pitch-b.Body.Pitch) < 1e-6 &&
+		math.Abs(a.Body.Yaw-b.Body.Yaw) < 1e-6
+}
+
+func TestEasings(t *testing.T) {
+	for _, easing := range []Easing{Linear, EaseIn, EaseOut, EaseInOut} {
+		if easing(0) != 0 || easing(1) != 1 {
+			t.Errorf("easing(0) = %v, easing(1) = %v, want 0 and 1", easing(0), easing(1))
+		}
+	}
+	if EaseIn(0.5) >= 0.5 || EaseOut(0.5) <= 0.5 || EaseInOut(0.5) != 0.5 {
+		t.Errorf("easings at 0.5 are %v, %v, %v", EaseIn(0.5), EaseOut(0.5), EaseInOut(0.5))
+	}
+}
+
+func TestMoveTo(t *testing.T) {
+	s := newTestSpider()
+	start := s.Pose()
+	target := testPose()
+	s.MoveTo(target, time.Second, Linear)
+	s.Update(500 * time.Millisecond)
+	if got, want := s.Pose(), lerpPose(start, target, 0.5); !posesEqual(got, want) {
+		t.Errorf("Pose() half way = %+v, want %+v", got, want)
+	}
+	if !s.Moving() {
+		t.Error("Moving() = false half way through")
+	}
+	s.Update(600 * time.Millisecond)
+	if got := s.Pose(); !posesEqual(got, target) {
+		t.Errorf("Pose() at end = %+v, want %+v", got, target)
+	}
+	if s.Moving() {
+		t.Error("Moving() = true after the move finished")
+	}
+}
+
+func TestMoveJointsTo(t *testing.T) {
+	s := newTestSpider()
+	target := testPose()
+	startAngles := s.poseAngles(s.Pose())
+	targetAngles := s.poseAngles(target)
+	s.MoveJointsTo(target, time.Second, Linear)
+	s.Update(500 * time.Millisecond)
+	// Half way through, every joint is half way between its start and end angles.
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		bc, cf, ft := s.legs[leg].JointAngles()
+		got := [3]float64{bc, cf, ft}
+		for j := range got {
+			if want := (startAngles[leg][j] + targetAngles[leg][j]) / 2; math.Abs(got[j]-want) > 1e-6 {
+				t.Errorf("leg %v joint %d is at %v half way, want %v", leg, j, got[j], want)
+			}
+		}
+	}
+	s.Update(500 * time.Millisecond)
+	if got := s.Pose(); !posesEqual(got, target) {
+		t.Errorf("Pose() at end = %+v, want %+v", got, target)
+	}
+}
+
+func TestCancelMove(t *testing.T) {
+	s := newTestSpider()
+	s.MoveTo(testPose(), time.Second, EaseInOut)
+	s.Update(300 * time.Millisecond)
+	s.CancelMove()
+	want := s.Pose()
+	s.Update(time.Second)
+	if got := s.Pose(); !posesEqual(got, want) || s.Moving() {
+		t.Errorf("Pose() after CancelMove = %+v, want %+v", got, want)
+	}
+}
+
+func TestBlendTo(t *testing.T) {
+	s := newTestSpider()
+	s.MoveTo(testPose(), time.Second, Linear)
+	s.Update(500 * time.Millisecond)
+
+	var target Pose
+	target.Feet[FrontRight] = Point3D{Y: 30}
+	s.BlendTo(target, time.Second, EaseInOut)
+	// A blend starts with no change of position.
+	before := s.Pose()
+	s.Update(0)
+	if got := s.Pose(); !posesEqual(got, before) {
+		t.Errorf("Pose() after starting blend = %+v, want %+v", got, before)
+	}
+	s.Update(time.Second)
+	if got := s.Pose(); !posesEqual(got, target) || s.Moving() {
+		t.Errorf("Pose() after blend = %+v, want %+v", got, target)
+	}
+}
+
+func TestBlendToAtControlRate(t *testing.T) {
+	s := newTestSpider()
+	// Retargeting every tick, as a joystick would, only keeps the moves which are still in progress.
+	for i := 0; i < 1000; i++ {
+		var target Pose
+		target.Feet[FrontRight] = Point3D{X: float64(i % 20)}
+		s.BlendTo(target, 100*time.Millisecond, Linear)
+		s.Update(10 * time.Millisecond)
+	}
+	depth := 0
+	for m := s.move; m != nil; m = m.blendFrom {
+		depth++
+	}
+	if depth > 11 {
+		t.Errorf("current move blends from a chain of %d moves, want no more than 11", depth)
+	}
+}
+
+func TestMoveToStopsWalking(t *testing.T) {
+	s := newTestSpider()
+	s.SetVelocity(10, 0, 0)
+	s.MoveTo(testPose(), time.Second, Linear)
+	if s.Walking() {
+		t.Error("Walking() = true after MoveTo")
+	}
+	s.SetVelocity(10, 0, 0)
+	if s.Moving() {
+		t.Error("Moving() = true after SetVelocity")
+	}
+}
+
+func TestMoveToLandsSwingingFeet(t *testing.T) {
+	s := newTestSpider()
+	s.SetVelocity(20, 0, 0)
+	var swinging LegPosition
+	found := false
+	for i := 0; i < 1000 && !found; i++ {
+		s.Update(10 * time.Millisecond)
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if s.LegState(leg) == Swing && s.ToePoint(leg).Z > 5 {
+				swinging, found = leg, true
+			}
+		}
+	}
+	if !found {
+		t.Fatal("no foot was lifted while walking")
+	}
+	lifted := s.ToePoint(swinging)
+	s.MoveTo(testPose(), time.Second, Linear)
+	for s.LegState(swinging) == Swing {
+		s.Update(10 * time.Millisecond)
+		// The foot goes straight down before the move starts.
+		if got := s.ToePoint(swinging); got.X != lifted.X || got.Y != lifted.Y || got.Z > lifted.Z {
+			t.Fatalf("while landing, the foot moved from %v to %v", lifted, got)
+		}
+	}
+	if got := s.ToePoint(swinging); got.Z != 0 {
+		t.Errorf("leg %v went into stance with its foot at %v", swinging, got)
+	}
+	runUntilIdle(t, s)
+	if got := s.Pose(); !posesEqual(got, testPose()) {
+		t.Errorf("after MoveTo, Pose() = %+v, want %+v", got, testPose())
+	}
+}
+
+func TestMoveJointsToBypassesIK(t *testing.T) {
+	s := newTestSpider()
+	target := testPose()
+	s.MoveJointsTo(target, time.Second, Linear)
+	s.Update(300 * time.Millisecond)
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		l := &s.legs[leg]
+		if !l.commanded {
+			t.Errorf("leg %d is using the IK part way through a joint-space move", leg)
+		}
+		// The feet reported by Pose match where the commanded angles put them.
+		want := s.body.toWorld(l.ToePointAt(l.cmdAngles).Add(l.origin)).Sub(l.origin)
+		if got := s.Pose().Feet[leg]; !approxEqualWithin(got, want, 1e-9) {
+			t.Errorf("leg %d Pose() foot = %v, want %v", leg, got, want)
+		}
+	}
+	s.SetAll(Point3D{})
+	if s.legs[FrontRight].commanded {
+		t.Error("SetAll() didn't return the legs to the IK")
+	}
+}
+
+func TestMove(t *testing.T) {
+	// A straight line between these two toe points passes too close to the hip.
+	from := Point3D{X: -20, Y: 30, Z: 30}
+	to := Point3D{X: 20, Y: 0, Z: 30}
+	tests := []struct {
+		interp  Interpolation
+		wantErr bool
+	}{
+		{Cartesian, true},
+		{JointSpace, false},
+	}
+	for _, tt := range tests {
+		s := newTestSpider()
+		s.SetToePoint(FrontRight, from)
+		target := s.Pose()
+		target.Feet[FrontRight] = to
+		err := s.Move(target, time.Second, Linear, tt.interp)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Move(%v) = %v, want error %v", tt.interp, err, tt.wantErr)
+		}
+		if s.Moving() == tt.wantErr {
+			t.Errorf("Move(%v): Moving() = %v, want %v", tt.interp, s.Moving(), !tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		runUntilIdle(t, s)
+		if got := s.Pose(); !posesEqual(got, target) {
+			t.Errorf("Move(%v): Pose() at end = %+v, want %+v", tt.interp, got, target)
+		}
+	}
+}
+
+func TestMoveRejectsUnreachableTarget(t *testing.T) {
+	for _, interp := range []Interpolation{Cartesian, JointSpace} {
+		s := newTestSpider()
+		target := s.Pose()
+		target.Feet[BackLeft] = Point3D{Z: -500}
+		if err := s.Move(target, time.Second, Linear, interp); err == nil {
+			t.Errorf("Move(%v) to an unreachable pose succeeded", interp)
+		}
+	}
+}
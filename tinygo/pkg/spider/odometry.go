@@ -0,0 +1,97 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"time"
+)
+
+// Odometry is the robot's estimate of where its body is on the ground, relative to where it started or was last reset.
+// Heading is how far the body has turned counter-clockwise, in radians. At a heading of zero the body faces along +Y,
+// as it does in its own frame.
+type Odometry struct {
+	X, Y    float64
+	Heading float64
+}
+
+// OdometryCorrection is called after every odometry update, and returns a corrected estimate.
+// It can be used to fuse in external sensors such as a compass or a camera.
+type OdometryCorrection func(estimate Odometry, dt time.Duration) Odometry
+
+func (s *Spider) Odometry() Odometry {
+	return s.odometry
+}
+
+// ResetOdometry sets the current estimate, e.g. to zero at the start of a run.
+func (s *Spider) ResetOdometry(o Odometry) {
+	s.odometry = o
+}
+
+// SetOdometryCorrection sets the correction hook. Nil removes it.
+func (s *Spider) SetOdometryCorrection(fn OdometryCorrection) {
+	s.correction = fn
+}
+
+// stanceFeet holds the body frame positions of the feet which stayed in stance for a whole tick, at the start and end
+// of it. It is a fixed size so that the control loop doesn't allocate.
+type stanceFeet struct {
+	before, after [4]Point3D
+	n             int
+}
+
+func (f *stanceFeet) add(before, after Point3D) {
+	f.before[f.n] = before
+	f.after[f.n] = after
+	f.n++
+}
+
+// updateOdometry moves the estimate by the opposite of the motion of the feet which stayed in stance, relative to the body.
+func (s *Spider) updateOdometry(feet *stanceFeet, dt time.Duration) {
+	if feet.n == 0 {
+		return
+	}
+	before, after := feet.before[:feet.n], feet.after[:feet.n]
+	var cb, ca Point3D
+	for i := range before {
+		cb = cb.Add(before[i])
+		ca = ca.Add(after[i])
+	}
+	cb = cb.Scale(1 / float64(feet.n))
+	ca = ca.Scale(1 / float64(feet.n))
+
+	// Fit a rotation about the centroids. With a single foot this is zero.
+	var sinSum, cosSum float64
+	for i := range before {
+		b := before[i].Sub(cb)
+		a := after[i].Sub(ca)
+		sinSum += b.X*a.Y - b.Y*a.X
+		cosSum += b.X*a.X + b.Y*a.Y
+	}
+	phi := math.Atan2(sinSum, cosSum)
+
+	// The feet moved by rotating phi about cb and then translating to ca, so the body did the opposite.
+	c, sn := math.Cos(-phi), math.Sin(-phi)
+	tx := cb.X - (c*ca.X - sn*ca.Y)
+	ty := cb.Y - (sn*ca.X + c*ca.Y)
+	h := s.odometry.Heading
+	s.odometry.X += math.Cos(h)*tx - math.Sin(h)*ty
+	s.odometry.Y += math.Sin(h)*tx + math.Cos(h)*ty
+	s.odometry.Heading = math.Remainder(h-phi, 2*math.Pi)
+
+	if s.correction != nil {
+		s.odometry = s.correction(s.odometry, dt)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// walkFor walks at the given velocity, and returns the pose that integrating the body velocity would give.
+func walkFor(s *Spider, vx, vy, omega float64, d time.Duration) Odometry {
+	const dt = 10 * time.Millisecond
+	var want Odometry
+	s.SetVelocity(vx, vy, omega)
+	for t := time.Duration(0); t < d; t += dt {
+		h := want.Heading
+		s.Update(dt)
+		x, y, w := s.Velocity()
+		want.X += (math.Cos(h)*x - math.Sin(h)*y) * dt.Seconds()
+		want.Y += (math.Sin(h)*x + math.Cos(h)*y) * dt.Seconds()
+		want.Heading += w * dt.Seconds()
+	}
+	return want
+}
+
+func TestOdometry(t *testing.T) {
+	tests := []struct {
+		vx, vy, omega float64
+	}{
+		{10, 0, 0},
+		{0, -8, 0},
+		{6, 6, 0},
+		{0, 0, 0.2},
+		{8, 0, 0.1},
+	}
+	for _, tt := range tests {
+		s := newTestSpider()
+		want := walkFor(s, tt.vx, tt.vy, tt.omega, 10*time.Second)
+		got := s.Odometry()
+		if math.Abs(got.X-want.X) > 1 || math.Abs(got.Y-want.Y) > 1 || math.Abs(got.Heading-want.Heading) > 0.01 {
+			t.Errorf("SetVelocity(%v, %v, %v): Odometry() = %+v, want %+v", tt.vx, tt.vy, tt.omega, got, want)
+		}
+	}
+}
+
+func TestOdometryIgnoresBodyPose(t *testing.T) {
+	s := newTestSpider()
+	s.SetBodyPose(Point3D{X: 10, Y: 5}, 0, 0, 0.2)
+	s.ShiftBody(-5, 0, time.Second)
+	runUntilIdle(t, s)
+	if got := s.Odometry(); got != (Odometry{}) {
+		t.Errorf("Odometry() after moving the body in place = %+v, want zero", got)
+	}
+}
+
+func TestResetOdometry(t *testing.T) {
+	s := newTestSpider()
+	walkFor(s, 10, 0, 0, time.Second)
+	s.ResetOdometry(Odometry{X: 100, Heading: math.Pi / 2})
+	before := s.Odometry()
+	walkFor(s, 10, 0, 0, time.Second)
+	got := s.Odometry()
+	// The body's X axis, which it walked along, now points along +Y.
+	if math.Abs(got.X-before.X) > 1e-6 || got.Y <= 0 {
+		t.Errorf("Odometry() after walking along X from heading Pi/2 = %+v, want movement along Y from %+v", got, before)
+	}
+}
+
+func TestOdometryCorrection(t *testing.T) {
+	s := newTestSpider()
+	calls := 0
+	s.SetOdometryCorrection(func(o Odometry, dt time.Duration) Odometry {
+		calls++
+		if dt != 10*time.Millisecond {
+			t.Errorf("correction called with dt = %v, want 10ms", dt)
+		}
+		// Pretend a compass says we are facing along Y.
+		o.Heading = 0
+		return o
+	})
+	walkFor(s, 0, 0, 0.3, time.Second)
+	if calls == 0 {
+		t.Fatal("correction was never called")
+	}
+	if h := s.Odometry().Heading; h != 0 {
+		t.Errorf("Odometry().Heading = %v, want the corrected value 0", h)
+	}
+}
+
+func TestOdometryFollowsAppliedGait(t *testing.T) {
+	tests := []struct {
+		gait Gait
+		// Fraction of each cycle that a foot spends in stance, sliding one stride backwards.
+		duty float64
+	}{
+		{NewCreepGait(testGaitParams), 1 - 1.0/8},
+		{NewPhaseGait(testGaitParams, TrotPattern), TrotPattern.DutyFactor},
+	}
+	const dt = 10 * time.Millisecond
+	for _, tt := range tests {
+		s := newTestSpider()
+		for i := 0; i < 3*int(testGaitParams.Period/dt); i++ {
+			tt.gait.Update(dt)
+			s.ApplyGait(tt.gait, dt)
+		}
+		got := s.Odometry()
+		want := 3 * testGaitParams.StrideLength / tt.duty
+		if math.Abs(got.Y-want) > 2 || math.Abs(got.X) > 1 || math.Abs(got.Heading) > 0.01 {
+			t.Errorf("%T: Odometry() after three cycles = %+v, want Y = %v", tt.gait, got, want)
+		}
+	}
+}
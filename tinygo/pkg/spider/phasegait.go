@@ -0,0 +1,107 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"time"
+)
+
+// GaitPattern is a phase table describing when each leg steps.
+// New gaits can be made by filling in a new table.
+type GaitPattern struct {
+	// Fraction of each cycle that a leg spends in stance.
+	DutyFactor float64
+	// Fraction of a cycle, for each leg, at which it touches down.
+	Offsets [4]float64
+}
+
+// Diagonal pairs step together.
+var TrotPattern = GaitPattern{
+	DutyFactor: 0.5,
+	Offsets: [4]float64{
+		FrontRight: 0,
+		FrontLeft:  0.5,
+		BackRight:  0.5,
+		BackLeft:   0,
+	},
+}
+
+// Legs on the same side step together.
+var PacePattern = GaitPattern{
+	DutyFactor: 0.5,
+	Offsets: [4]float64{
+		FrontRight: 0,
+		FrontLeft:  0.5,
+		BackRight:  0,
+		BackLeft:   0.5,
+	},
+}
+
+// One leg steps at a time, in the same order as CreepGait, but without shifting the body.
+var WalkPattern = GaitPattern{
+	DutyFactor: 0.75,
+	Offsets: [4]float64{
+		BackRight:  0.25,
+		FrontRight: 0.5,
+		BackLeft:   0.75,
+		FrontLeft:  0,
+	},
+}
+
+// PhaseGait is a gait defined entirely by a GaitPattern.
+// It doesn't shift the body, so it relies on speed for balance unless at least three feet are always down.
+type PhaseGait struct {
+	params  GaitParams
+	pattern GaitPattern
+	// Fraction of the way through the cycle, in [0, 1).
+	phase float64
+}
+
+func NewPhaseGait(params GaitParams, pattern GaitPattern) *PhaseGait {
+	return &PhaseGait{
+		params:  params,
+		pattern: pattern,
+	}
+}
+
+// Update advances the gait by the given amount of time.
+func (g *PhaseGait) Update(dt time.Duration) {
+	if g.params.Period <= 0 {
+		return
+	}
+	g.phase += float64(dt) / float64(g.params.Period)
+	g.phase -= math.Floor(g.phase)
+}
+
+func (g *PhaseGait) Phase() float64 {
+	return g.phase
+}
+
+// LegPhase returns the fraction of a cycle since the leg last touched down.
+func (g *PhaseGait) LegPhase(leg LegPosition) float64 {
+	p := g.phase - g.pattern.Offsets[leg]
+	return p - math.Floor(p)
+}
+
+// InSwing reports whether the leg is currently in the air.
+func (g *PhaseGait) InSwing(leg LegPosition) bool {
+	return g.LegPhase(leg) >= g.pattern.DutyFactor
+}
+
+// ToePoint returns the current toe point for a leg.
+func (g *PhaseGait) ToePoint(leg LegPosition) Point3D {
+	return stridePoint(g.params, g.pattern.DutyFactor, g.LegPhase(leg))
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPhaseGaitPairsMoveTogether(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern GaitPattern
+		pairs   [2][2]LegPosition
+	}{
+		{"trot", TrotPattern, [2][2]LegPosition{{FrontRight, BackLeft}, {FrontLeft, BackRight}}},
+		{"pace", PacePattern, [2][2]LegPosition{{FrontRight, BackRight}, {FrontLeft, BackLeft}}},
+	}
+	for _, tt := range tests {
+		g := NewPhaseGait(testGaitParams, tt.pattern)
+		for i := 0; i < 400; i++ {
+			for _, pair := range tt.pairs {
+				if g.InSwing(pair[0]) != g.InSwing(pair[1]) || g.ToePoint(pair[0]) != g.ToePoint(pair[1]) {
+					t.Errorf("%s: legs %v and %v are out of step at phase %v", tt.name, pair[0], pair[1], g.Phase())
+				}
+			}
+			// The two pairs are half a cycle apart.
+			a, b := g.LegPhase(tt.pairs[0][0]), g.LegPhase(tt.pairs[1][0])
+			if d := math.Abs(a - b); math.Abs(d-0.5) > 1e-9 {
+				t.Errorf("%s: pairs are %v of a cycle apart at phase %v, want 0.5", tt.name, d, g.Phase())
+			}
+			g.Update(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestPhaseGaitStanceCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   GaitPattern
+		minStance int
+	}{
+		{"trot", TrotPattern, 2},
+		{"pace", PacePattern, 2},
+		{"walk", WalkPattern, 3},
+		{"slow trot", GaitPattern{DutyFactor: 0.6, Offsets: TrotPattern.Offsets}, 2},
+	}
+	for _, tt := range tests {
+		g := NewPhaseGait(testGaitParams, tt.pattern)
+		for i := 0; i < 400; i++ {
+			stance := 0
+			for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+				if !g.InSwing(leg) {
+					stance++
+				}
+			}
+			if stance < tt.minStance {
+				t.Errorf("%s: only %d feet in stance at phase %v, want at least %d", tt.name, stance, g.Phase(), tt.minStance)
+			}
+			g.Update(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestPhaseGaitTrajectoriesAreContinuous(t *testing.T) {
+	g := NewPhaseGait(testGaitParams, TrotPattern)
+	var prev [4]Point3D
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		prev[leg] = g.ToePoint(leg)
+	}
+	for i := 0; i < 400; i++ {
+		g.Update(10 * time.Millisecond)
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			pt := g.ToePoint(leg)
+			if d := pt.Sub(prev[leg]); math.Sqrt(d.X*d.X+d.Y*d.Y+d.Z*d.Z) > 2 {
+				t.Errorf("leg %v jumped from %v to %v at phase %v", leg, prev[leg], pt, g.Phase())
+			}
+			prev[leg] = pt
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"time"
+)
+
+// PowerOn brings the robot up from an unknown position without snapping every servo at once.
+// It assumes the robot is resting on its body, turns off all the servos, and then, on subsequent calls to Update,
+// turns on one leg at a time in the parked pose before standing up to the given body height.
+// Half of the duration is spent turning on the legs and half standing up.
+func (s *Spider) PowerOn(bodyHeight float64, duration time.Duration) {
+	s.CancelMove()
+	s.stopWalking()
+	s.body = BodyPose{}
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		s.unpowered[leg] = true
+		s.feet[leg] = s.parkToePoint(leg)
+		// The feet have been put wherever the robot is assumed to be, so every leg is parked, even one that the gait
+		// had in the air.
+		s.legs[leg].state = Parked
+	}
+	s.updateLegs()
+
+	// Each leg gets a quarter of the first half to reach the parked pose before the next one starts.
+	step := duration / 8
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		leg := leg
+		s.queueMove(func(s *Spider, p Pose) Pose {
+			s.unpowered[leg] = false
+			return p
+		}, step, Linear)
+	}
+	s.StandUp(bodyHeight, duration-4*step)
+}
+
+// PowerOff parks, and then turns off all the servos.
+// Park lowers the body and spreads the feet together: the legs can't reach the park height with the feet still
+// under the hips.
+func (s *Spider) PowerOff(duration time.Duration) {
+	s.Park(duration)
+	s.queueMove(func(s *Spider, p Pose) Pose {
+		s.unpowered = [4]bool{true, true, true, true}
+		return p
+	}, 0, Linear)
+}
+
+// Powered reports whether a leg's servos are turned on.
+func (s *Spider) Powered(leg LegPosition) bool {
+	return !s.unpowered[leg]
+}
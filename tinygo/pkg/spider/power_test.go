@@ -0,0 +1,121 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// runFrames runs the control loop until the spider stops moving, sending a frame on every tick.
+// Every frame has to be sent without errors, including joints being clamped to their servo's range.
+func runFrames(t *testing.T, s *Spider) {
+	t.Helper()
+	for i := 0; i < 10000 && s.Moving(); i++ {
+		s.Update(10 * time.Millisecond)
+		if err := s.SendCommandsToServos(); err != nil {
+			t.Fatalf("SendCommandsToServos() in frame %d = %v", i, err)
+		}
+	}
+	if s.Moving() {
+		t.Fatal("Moving() is still true after 100s")
+	}
+}
+
+// legPulses returns the pulses of a leg's three servos in a recorded frame.
+func legPulses(s *Spider, frame map[byte]uint16, leg LegPosition) [3]uint16 {
+	var p [3]uint16
+	for joint := BodyCoxa; joint <= FemurTibia; joint++ {
+		p[joint] = frame[s.servos[servoId(leg, joint)].Pin()]
+	}
+	return p
+}
+
+func TestPowerOn(t *testing.T) {
+	r := NewRecorder()
+	s := New(r, DefaultConfig())
+	s.PowerOn(TibiaLength, 2*time.Second)
+	if err := s.SendCommandsToServos(); err != nil {
+		t.Fatalf("SendCommandsToServos() after PowerOn() = %v", err)
+	}
+	runFrames(t, s)
+
+	// Find the first frame in which each leg was turned on.
+	var on [4]int
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		on[leg] = -1
+		for i, frame := range r.Frames {
+			if legPulses(s, frame, leg) != [3]uint16{} {
+				on[leg] = i
+				break
+			}
+		}
+		if on[leg] < 1 {
+			t.Fatalf("leg %d was turned on in frame %d, want after the first frame", leg, on[leg])
+		}
+		// The leg is turned on straight into the parked pose.
+		s2 := newTestSpider()
+		s2.feet[leg] = s2.parkToePoint(leg)
+		s2.updateLegs()
+		s2.SendCommandsToServos()
+		want := legPulses(s2, s2.act.(*Recorder).Frames[0], leg)
+		if got := legPulses(s, r.Frames[on[leg]], leg); got != want {
+			t.Errorf("leg %d was turned on at %v, want the parked pose %v", leg, got, want)
+		}
+	}
+	for leg := LegPosition(1); leg < LegPosition(4); leg++ {
+		if on[leg] <= on[leg-1] {
+			t.Errorf("legs were turned on in frames %v, want one at a time", on)
+			break
+		}
+	}
+
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if !s.Powered(leg) || s.LegState(leg) != Stance {
+			t.Errorf("leg %d is powered=%v in state %v after PowerOn(), want powered and in stance", leg, s.Powered(leg), s.LegState(leg))
+		}
+	}
+	if h := bodyHeight(s); math.Abs(h-TibiaLength) > 1e-9 {
+		t.Errorf("body height after PowerOn() = %v, want %v", h, TibiaLength)
+	}
+}
+
+func TestPowerOff(t *testing.T) {
+	r := NewRecorder()
+	s := New(r, DefaultConfig())
+	s.PowerOff(2 * time.Second)
+	runFrames(t, s)
+
+	last := r.Frames[len(r.Frames)-1]
+	for _, servo := range s.servos {
+		if got := last[servo.Pin()]; got != 0 {
+			t.Errorf("channel %d pulse = %d after PowerOff(), want 0", servo.Pin(), got)
+		}
+	}
+	// The servos stay on until the robot has parked.
+	parked := r.Frames[len(r.Frames)-2]
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if legPulses(s, parked, leg) == [3]uint16{} {
+			t.Errorf("leg %d was turned off before it parked", leg)
+		}
+		if got, want := s.ToePoint(leg), s.parkToePoint(leg); !approxEqual(got, want) {
+			t.Errorf("leg %d toe point after PowerOff() = %v, want %v", leg, got, want)
+		}
+		if s.Powered(leg) || s.LegState(leg) != Parked {
+			t.Errorf("leg %d is powered=%v in state %v after PowerOff(), want unpowered and parked", leg, s.Powered(leg), s.LegState(leg))
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"time"
+)
+
+// Body height for Park. This is about as low as the body can go with every joint inside its servo's range.
+const ParkHeight = 45.0
+
+// How far each foot is pushed out from the body for Park. The legs can't fold tightly enough to keep the feet
+// under the hips with the body that low.
+const parkSpread = 35.0
+
+// The motion primitives below queue moves, which happen on subsequent calls to Update.
+// Each one starts from wherever the previous one finished, so they can be chained freely.
+// If a primitive finds that a leg can't change to the state it needs, such as lifting a parked leg, or lifting a leg
+// would be unstable, it stops there and the rest of the queue is dropped. QueueError then returns the reason.
+
+// StandUp moves every foot back under its hip and raises or lowers the body to the given height, with the body level.
+// Parked and lifted legs go into stance.
+func (s *Spider) StandUp(bodyHeight float64, duration time.Duration) {
+	s.queueMove(func(s *Spider, p Pose) Pose {
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if !s.setQueuedLegState(leg, Stance) {
+				return p
+			}
+		}
+		for leg := range p.Feet {
+			p.Feet[leg] = Point3D{Z: TibiaLength - bodyHeight}
+		}
+		p.Body = BodyPose{}
+		return p
+	}, duration, EaseInOut)
+}
+
+// SitDown lowers the body to the given height, keeping the feet where they are.
+func (s *Spider) SitDown(bodyHeight float64, duration time.Duration) {
+	s.queueMove(func(s *Spider, p Pose) Pose {
+		// The body is at the height of the lowest foot, plus the canonical height of the hips.
+		ground := math.Inf(1)
+		for leg := range p.Feet {
+			ground = math.Min(ground, p.Feet[leg].Z)
+		}
+		p.Body.Translation.Z = ground + bodyHeight - TibiaLength
+		return p
+	}, duration, EaseInOut)
+}
+
+// LegUp raises a foot by the given height. Lifting a foot which is on the ground has the same stability check as
+// LiftLeg, so use ShiftWeightOffLeg first.
+func (s *Spider) LegUp(leg LegPosition, height float64, duration time.Duration) {
+	s.queueMove(func(s *Spider, p Pose) Pose {
+		if !s.queuedLift(leg) {
+			return p
+		}
+		p.Feet[leg].Z += height
+		return p
+	}, duration, EaseInOut)
+}
+
+// LegDown lowers a foot until it is level with the lowest of the other feet.
+func (s *Spider) LegDown(leg LegPosition, duration time.Duration) {
+	s.queueMove(func(s *Spider, p Pose) Pose {
+		ground := math.Inf(1)
+		for other := range p.Feet {
+			if LegPosition(other) != leg {
+				ground = math.Min(ground, p.Feet[other].Z)
+			}
+		}
+		p.Feet[leg].Z = ground
+		return p
+	}, duration, EaseInOut)
+	s.queueMove(func(s *Spider, p Pose) Pose {
+		s.setQueuedLegState(leg, Stance)
+		return p
+	}, 0, Linear)
+}
+
+// ShiftBody moves the body horizontally by the given amount, keeping the feet planted.
+func (s *Spider) ShiftBody(x, y float64, duration time.Duration) {
+	s.queueMove(func(s *Spider, p Pose) Pose {
+		p.Body.Translation = p.Body.Translation.Add(Point3D{X: x, Y: y})
+		return p
+	}, duration, EaseInOut)
+}
+
+// ShiftWeightOffLeg moves the body diagonally away from a leg by the given distance, so that the leg can be lifted.
+// A negative distance moves the body back towards the leg.
+func (s *Spider) ShiftWeightOffLeg(leg LegPosition, distance float64, duration time.Duration) {
+	origin := s.legs[leg].origin
+	d := distance / math.Sqrt(origin.X*origin.X+origin.Y*origin.Y)
+	s.ShiftBody(-origin.X*d, -origin.Y*d, duration)
+}
+
+// Wave shifts the weight off a leg, raises it out in front, waves it from side to side the given number of times,
+// then puts everything back where it was. A negative number of times counts as zero.
+func (s *Spider) Wave(leg LegPosition, times int, duration time.Duration) {
+	const shift = 20.0
+	const lift = 50.0
+	const swing = 20.0
+	if times < 0 {
+		times = 0
+	}
+	// Split the time between getting into position, waving, and getting back.
+	step := duration / time.Duration(4+2*times)
+	origin := s.legs[leg].origin
+	outward := Point3D{X: origin.X, Y: origin.Y}.Scale(1 / math.Sqrt(origin.X*origin.X+origin.Y*origin.Y))
+	across := Point3D{X: -outward.Y, Y: outward.X}
+
+	var planted Point3D
+	s.ShiftWeightOffLeg(leg, shift, step)
+	s.queueMove(func(s *Spider, p Pose) Pose {
+		planted = p.Feet[leg]
+		if !s.queuedLift(leg) {
+			return p
+		}
+		p.Feet[leg] = planted.Add(outward.Scale(swing)).Add(Point3D{Z: lift})
+		return p
+	}, step, EaseInOut)
+	for i := 0; i < times; i++ {
+		for _, dir := range []float64{1, -1} {
+			dir := dir
+			s.queueMove(func(s *Spider, p Pose) Pose {
+				p.Feet[leg] = planted.Add(outward.Scale(swing)).Add(across.Scale(dir * swing)).Add(Point3D{Z: lift})
+				return p
+			}, step, EaseInOut)
+		}
+	}
+	s.queueMove(func(s *Spider, p Pose) Pose {
+		if !s.setQueuedLegState(leg, Stance) {
+			return p
+		}
+		p.Feet[leg] = planted
+		return p
+	}, step, EaseInOut)
+	s.ShiftWeightOffLeg(leg, -shift, step)
+}
+
+// Park lowers the body onto the ground with the legs folded flat, ready for the power to be turned off.
+// The legs count as parked once the move has finished.
+func (s *Spider) Park(duration time.Duration) {
+	s.queueMove(func(s *Spider, p Pose) Pose {
+		for leg := range p.Feet {
+			p.Feet[leg] = s.parkToePoint(LegPosition(leg))
+		}
+		p.Body = BodyPose{}
+		return p
+	}, duration, EaseInOut)
+	s.queueMove(func(s *Spider, p Pose) Pose {
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			s.setQueuedLegState(leg, Parked)
+		}
+		return p
+	}, 0, Linear)
+}
+
+// setQueuedLegState changes a leg's state from inside a queued move. If the change isn't allowed, it drops the rest
+// of the queue, so that the primitive stops where it is, and returns false.
+func (s *Spider) setQueuedLegState(leg LegPosition, st LegState) bool {
+	if err := s.SetLegState(leg, st); err != nil {
+		s.abortQueue(err)
+		return false
+	}
+	return true
+}
+
+// queuedLift is like setQueuedLegState, but goes through LiftLeg's stability check. The queued move raises the foot.
+func (s *Spider) queuedLift(leg LegPosition) bool {
+	if err := s.LiftLeg(leg, 0); err != nil {
+		s.abortQueue(err)
+		return false
+	}
+	return true
+}
+
+// abortQueue drops the queued moves, and records why.
+func (s *Spider) abortQueue(err error) {
+	s.queue = nil
+	s.queueErr = err
+}
+
+// QueueError returns the error which stopped the last queued primitive, if any. It is cleared when a primitive is
+// queued with nothing else moving.
+func (s *Spider) QueueError() error {
+	return s.queueErr
+}
+
+// parkToePoint returns the toe point for a leg in the parked pose.
+func (s *Spider) parkToePoint(leg LegPosition) Point3D {
+	origin := s.legs[leg].origin
+	outward := Point3D{X: origin.X, Y: origin.Y}.Scale(parkSpread / math.Sqrt(origin.X*origin.X+origin.Y*origin.Y))
+	return outward.Add(Point3D{Z: TibiaLength - ParkHeight})
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// runUntilIdle ticks the spider until it has finished all of its moves.
+func runUntilIdle(t *testing.T, s *Spider) {
+	t.Helper()
+	for i := 0; i < 10000 && s.Moving(); i++ {
+		s.Update(10 * time.Millisecond)
+	}
+	if s.Moving() {
+		t.Fatal("Moving() is still true after 100s")
+	}
+}
+
+// bodyHeight returns the height of the hips above the lowest foot.
+func bodyHeight(s *Spider) float64 {
+	ground := math.Inf(1)
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		ground = math.Min(ground, s.ToePoint(leg).Z)
+	}
+	return TibiaLength + s.BodyPose().Translation.Z - ground
+}
+
+func TestStandUpAndSitDown(t *testing.T) {
+	s := newTestSpider()
+	s.SetAll(Point3D{X: 10, Y: -5, Z: 20})
+	s.StandUp(70, time.Second)
+	runUntilIdle(t, s)
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if got, want := s.ToePoint(leg), (Point3D{Z: TibiaLength - 70}); !approxEqual(got, want) {
+			t.Errorf("after StandUp, leg %v is at %v, want %v", leg, got, want)
+		}
+	}
+	if got := bodyHeight(s); math.Abs(got-70) > 1e-9 {
+		t.Errorf("after StandUp, body height is %v, want 70", got)
+	}
+
+	s.SitDown(40, time.Second)
+	runUntilIdle(t, s)
+	if got := bodyHeight(s); math.Abs(got-40) > 1e-9 {
+		t.Errorf("after SitDown, body height is %v, want 40", got)
+	}
+}
+
+func TestLegUpAndDownChain(t *testing.T) {
+	s := newTestSpider()
+	s.ShiftWeightOffLeg(FrontLeft, 10, 200*time.Millisecond)
+	s.LegUp(FrontLeft, 10, 200*time.Millisecond)
+	s.LegUp(FrontLeft, 10, 200*time.Millisecond)
+	runUntilIdle(t, s)
+	if got := s.ToePoint(FrontLeft).Z; got != 20 {
+		t.Errorf("after two LegUps, the foot is at height %v, want 20", got)
+	}
+	if s.stance()[FrontLeft] {
+		t.Error("after LegUp, the foot is counted as in stance")
+	}
+	s.LegDown(FrontLeft, 200*time.Millisecond)
+	runUntilIdle(t, s)
+	if got := s.ToePoint(FrontLeft).Z; got != 0 {
+		t.Errorf("after LegDown, the foot is at height %v, want 0", got)
+	}
+	if !s.stance()[FrontLeft] {
+		t.Error("after LegDown, the foot is not counted as in stance")
+	}
+}
+
+func TestShiftWeightOffLeg(t *testing.T) {
+	s := newTestSpider()
+	s.ShiftWeightOffLeg(BackRight, 20, 200*time.Millisecond)
+	runUntilIdle(t, s)
+	if got := s.BodyPose().Translation; got.X >= 0 || got.Y <= 0 || math.Abs(math.Sqrt(got.X*got.X+got.Y*got.Y)-20) > 1e-9 {
+		t.Errorf("after ShiftWeightOffLeg(BackRight), the body is at %v", got)
+	}
+	if m := s.LiftMargin(BackRight); m <= 0 {
+		t.Errorf("after ShiftWeightOffLeg(BackRight), LiftMargin() = %v, want positive", m)
+	}
+	s.ShiftBody(1, 2, 200*time.Millisecond)
+	s.ShiftWeightOffLeg(BackRight, -20, 200*time.Millisecond)
+	runUntilIdle(t, s)
+	if got, want := s.BodyPose().Translation, (Point3D{X: 1, Y: 2}); !approxEqual(got, want) {
+		t.Errorf("after shifting back, the body is at %v, want %v", got, want)
+	}
+}
+
+func TestWaveReturnsToStart(t *testing.T) {
+	s := newTestSpider()
+	s.StandUp(70, time.Second)
+	runUntilIdle(t, s)
+	start := s.Pose()
+
+	s.Wave(FrontRight, 2, 4*time.Second)
+	maxHeight := 0.0
+	for i := 0; i < 1000 && s.Moving(); i++ {
+		s.Update(10 * time.Millisecond)
+		maxHeight = math.Max(maxHeight, s.ToePoint(FrontRight).Z-start.Feet[FrontRight].Z)
+		if !s.stance()[FrontRight] && s.StabilityMargin() <= 0 {
+			t.Fatalf("StabilityMargin() = %v while waving", s.StabilityMargin())
+		}
+	}
+	if maxHeight < 40 {
+		t.Errorf("the foot only went %v high", maxHeight)
+	}
+	if got := s.Pose(); !posesEqual(got, start) {
+		t.Errorf("after Wave, Pose() = %+v, want %+v", got, start)
+	}
+}
+
+func TestPark(t *testing.T) {
+	s := newTestSpider()
+	s.StandUp(70, time.Second)
+	s.Park(time.Second)
+	runUntilIdle(t, s)
+	if got := bodyHeight(s); math.Abs(got-ParkHeight) > 1e-9 {
+		t.Errorf("after Park, body height is %v, want %v", got, ParkHeight)
+	}
+	// Every joint has to be inside its servo's range, or the servos would be clamped short of the pose.
+	if err := s.CheckReachable(s.Pose()); err != nil {
+		t.Errorf("after Park, CheckReachable() = %v", err)
+	}
+}
+
+func TestWaveNegativeTimes(t *testing.T) {
+	for _, times := range []int{-1, -2, -5} {
+		s := newTestSpider()
+		start := s.Pose()
+		s.Wave(FrontRight, times, 4*time.Second)
+		runUntilIdle(t, s)
+		if got := s.Pose(); !posesEqual(got, start) {
+			t.Errorf("after Wave(%d), Pose() = %+v, want %+v", times, got, start)
+		}
+	}
+}
+
+func TestLegUpChecksStability(t *testing.T) {
+	s := newTestSpider()
+	// Without leaning away first, the centre of mass is on the edge of the remaining support triangle.
+	s.LegUp(FrontLeft, 20, 200*time.Millisecond)
+	s.LegDown(FrontLeft, 200*time.Millisecond)
+	runUntilIdle(t, s)
+	if got := s.ToePoint(FrontLeft).Z; got != 0 {
+		t.Errorf("LegUp() raised the foot to %v without enough margin", got)
+	}
+	if err := s.QueueError(); err != ErrUnstable {
+		t.Errorf("QueueError() = %v, want ErrUnstable", err)
+	}
+	s.ShiftWeightOffLeg(FrontLeft, 10, 200*time.Millisecond)
+	if err := s.QueueError(); err != nil {
+		t.Errorf("QueueError() after queueing a new primitive = %v, want nil", err)
+	}
+}
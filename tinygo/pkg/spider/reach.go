@@ -0,0 +1,51 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"fmt"
+	"math"
+)
+
+// CheckReachable returns an error if any leg can't reach its toe point in the given pose without its servos
+// hitting their limits.
+func (s *Spider) CheckReachable(p Pose) error {
+	body := p.Body.transform()
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		joint, solved := s.unreachableJoint(leg, p.Feet[leg], &body)
+		if !solved {
+			return fmt.Errorf("spider: leg %d can't reach %v", leg, p.Feet[leg])
+		}
+		if joint >= 0 {
+			return fmt.Errorf("spider: leg %d joint %d is out of range at %v", leg, joint, p.Feet[leg])
+		}
+	}
+	return nil
+}
+
+// unreachableJoint returns the first joint of a leg whose servo would be past its limits to reach the toe point, or
+// -1 if there isn't one, and false if the IK has no solution at all. It is shared by CheckReachable and ToeReachable,
+// and doesn't allocate, since the reach map calls it for every point on a grid.
+func (s *Spider) unreachableJoint(leg LegPosition, foot Point3D, body *bodyTransform) (int, bool) {
+	for joint, angle := range s.legAngles(leg, foot, body) {
+		if math.IsNaN(angle) {
+			return joint, false
+		}
+		if !s.servos[servoId(leg, Joint(joint))].InRange(angle) {
+			return joint, true
+		}
+	}
+	return -1, true
+}
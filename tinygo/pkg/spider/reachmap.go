@@ -0,0 +1,85 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+)
+
+// Resolution of the search in MaxStride.
+const strideResolution = 1.0
+
+// ToeReachable reports whether a leg can reach a toe point, with the body in its neutral pose, without any of its
+// servos hitting their limits.
+func (s *Spider) ToeReachable(leg LegPosition, pt Point3D) bool {
+	body := BodyPose{}.transform()
+	joint, solved := s.unreachableJoint(leg, pt, &body)
+	return solved && joint < 0
+}
+
+// ZeroToePoint returns the position of a leg's canonical zero toe point in the body frame.
+// Adding it to a toe point gives the position of the toe relative to the centre of the body.
+func (s *Spider) ZeroToePoint(leg LegPosition) Point3D {
+	return s.legs[leg].origin
+}
+
+// WorkspaceBounds returns a box of toe points which contains everything the leg could reach with unlimited servos.
+func (s *Spider) WorkspaceBounds(leg LegPosition) (Point3D, Point3D) {
+	const reach = CoxaLength + FemurLength + TibiaLength
+	hip := s.legs[leg].hipPt
+	return hip.Sub(Point3D{X: reach, Y: reach, Z: FemurLength + TibiaLength}),
+		hip.Add(Point3D{X: reach, Y: reach, Z: FemurLength + TibiaLength})
+}
+
+// ReachableToePoints sweeps a box of toe points on a grid with the given spacing, and returns the ones the leg can reach.
+// It returns nil if the spacing isn't positive.
+func (s *Spider) ReachableToePoints(leg LegPosition, min, max Point3D, step float64) []Point3D {
+	if !(step > 0) {
+		return nil
+	}
+	var pts []Point3D
+	for x := min.X; x <= max.X; x += step {
+		for y := min.Y; y <= max.Y; y += step {
+			for z := min.Z; z <= max.Z; z += step {
+				if pt := (Point3D{X: x, Y: y, Z: z}); s.ToeReachable(leg, pt) {
+					pts = append(pts, pt)
+				}
+			}
+		}
+	}
+	return pts
+}
+
+// MaxStride returns the longest stride for which every foot can reach the whole of its stance path at the given
+// body height, with the stride centred under the hip as it is in the gaits. The result is rounded down to a multiple
+// of strideResolution, and is zero if the feet can't reach the ground at all.
+func (s *Spider) MaxStride(bodyHeight float64) float64 {
+	const step = strideResolution / 2
+	neutral := Point3D{Z: TibiaLength - bodyHeight}
+	half := math.Inf(1)
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if !s.ToeReachable(leg, neutral) {
+			return 0
+		}
+		for _, dir := range []float64{1, -1} {
+			d := 0.0
+			for d < half && s.ToeReachable(leg, neutral.Add(Point3D{Y: dir * (d + step)})) {
+				d += step
+			}
+			half = d
+		}
+	}
+	return 2 * half
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+)
+
+func TestToeReachable(t *testing.T) {
+	s := newTestSpider()
+	tests := []struct {
+		pt   Point3D
+		want bool
+	}{
+		{Point3D{}, true},
+		{Point3D{X: 5, Y: 5, Z: -10}, true},
+		{Point3D{Z: -500}, false},
+		{Point3D{X: -80, Y: -80}, false},
+	}
+	for _, tt := range tests {
+		if got := s.ToeReachable(FrontRight, tt.pt); got != tt.want {
+			t.Errorf("ToeReachable(FrontRight, %v) = %v, want %v", tt.pt, got, tt.want)
+		}
+	}
+}
+
+func TestReachableToePoints(t *testing.T) {
+	s := newTestSpider()
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		min, max := s.WorkspaceBounds(leg)
+		pts := s.ReachableToePoints(leg, min, max, 10)
+		if len(pts) == 0 {
+			t.Fatalf("ReachableToePoints(%d) found nothing", leg)
+		}
+		for _, pt := range pts {
+			if !s.ToeReachable(leg, pt) {
+				t.Errorf("ReachableToePoints(%d) returned unreachable point %v", leg, pt)
+			}
+			if pt.X < min.X || pt.Y < min.Y || pt.Z < min.Z || pt.X > max.X || pt.Y > max.Y || pt.Z > max.Z {
+				t.Errorf("ReachableToePoints(%d) returned %v, outside %v to %v", leg, pt, min, max)
+			}
+		}
+	}
+}
+
+func TestReachableToePointsBadStep(t *testing.T) {
+	s := newTestSpider()
+	min, max := s.WorkspaceBounds(FrontRight)
+	for _, step := range []float64{0, -5, math.NaN()} {
+		if pts := s.ReachableToePoints(FrontRight, min, max, step); pts != nil {
+			t.Errorf("ReachableToePoints() with step %v returned %d points, want nil", step, len(pts))
+		}
+	}
+}
+
+func TestWorkspaceBoundsContainReach(t *testing.T) {
+	s := newTestSpider()
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		min, max := s.WorkspaceBounds(leg)
+		// Every reachable point found by a coarse sweep of a much bigger box is inside the bounds.
+		big := Point3D{X: 400, Y: 400, Z: 400}
+		for _, pt := range s.ReachableToePoints(leg, big.Scale(-1), big, 20) {
+			if pt.X < min.X || pt.Y < min.Y || pt.Z < min.Z || pt.X > max.X || pt.Y > max.Y || pt.Z > max.Z {
+				t.Errorf("leg %d can reach %v, outside WorkspaceBounds() %v to %v", leg, pt, min, max)
+			}
+		}
+	}
+}
+
+func TestMaxStride(t *testing.T) {
+	s := newTestSpider()
+	stride := s.MaxStride(TibiaLength)
+	if stride <= 0 {
+		t.Fatalf("MaxStride(%v) = %v, want positive", TibiaLength, stride)
+	}
+	reachesAll := func(stride float64) bool {
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			for y := -stride / 2; y <= stride/2; y += 0.25 {
+				if !s.ToeReachable(leg, Point3D{Y: y}) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if !reachesAll(stride) {
+		t.Errorf("MaxStride(%v) = %v, but some feet can't reach the whole stride", TibiaLength, stride)
+	}
+	if reachesAll(stride + 2*strideResolution) {
+		t.Errorf("MaxStride(%v) = %v, but a longer stride is reachable", TibiaLength, stride)
+	}
+	if got := s.MaxStride(500); got != 0 {
+		t.Errorf("MaxStride(500) = %v, want 0", got)
+	}
+}
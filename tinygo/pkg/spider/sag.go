@@ -0,0 +1,130 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+)
+
+// SagModel describes how far the joints of a stance leg sag under load. Each value is the offset, in radians, added
+// to the commanded joint angle when the leg carries the whole weight of the robot, and it is scaled down in
+// proportion to the leg's share of the load. The signs depend on the servos, so measure them on the robot.
+// The zero value disables compensation.
+type SagModel struct {
+	CoxaFemur  float64
+	FemurTibia float64
+}
+
+// SetSagModel sets the model used to compensate for joints sagging under load.
+func (s *Spider) SetSagModel(m SagModel) {
+	s.sag = m
+}
+
+// LoadShares returns the fraction of the weight carried by each of up to four feet, for a centre of mass at com.
+// The shares are in the same order as the feet, and any left over are zero.
+// With three feet, this is the static solution. With four, the problem is statically indeterminate, so it returns the
+// most even distribution which balances the robot. If the centre of mass is outside the support polygon, feet which
+// would have to pull on the ground carry nothing. Feet in a straight line share the load equally.
+func LoadShares(feet []Point3D, com Point3D) [4]float64 {
+	n := len(feet)
+	var shares [4]float64
+	switch n {
+	case 0:
+		return shares
+	case 1:
+		shares[0] = 1
+		return shares
+	case 2:
+		// Split in proportion to how close the centre of mass is to each foot along the line between them.
+		ab := Point3D{X: feet[1].X - feet[0].X, Y: feet[1].Y - feet[0].Y}
+		t := 0.5
+		if lenSq := ab.X*ab.X + ab.Y*ab.Y; lenSq > 0 {
+			t = clamp(((com.X-feet[0].X)*ab.X+(com.Y-feet[0].Y)*ab.Y)/lenSq, 0, 1)
+		}
+		shares[0], shares[1] = 1-t, t
+		return shares
+	}
+
+	// Minimise the sum of the squared shares, subject to them adding up to one and balancing the centre of mass.
+	// The solution is w = A^T * (A*A^T)^-1 * b, where each column of A is (1, x, y) for a foot and b is (1, com.X, com.Y).
+	var aat [3][3]float64
+	for _, f := range feet {
+		row := [3]float64{1, f.X, f.Y}
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 3; c++ {
+				aat[r][c] += row[r] * row[c]
+			}
+		}
+	}
+	if math.Abs(det3(aat)) < 1e-9 {
+		for i := range feet {
+			shares[i] = 1 / float64(n)
+		}
+		return shares
+	}
+	lambda := solve3(aat, [3]float64{1, com.X, com.Y})
+	total := 0.0
+	for i, f := range feet {
+		shares[i] = math.Max(lambda[0]+lambda[1]*f.X+lambda[2]*f.Y, 0)
+		total += shares[i]
+	}
+	for i := range feet {
+		shares[i] /= total
+	}
+	return shares
+}
+
+// LoadShare returns the fraction of the robot's weight carried by each foot, using the feet which are in stance.
+// The shares are NaN if the centre of mass is unknown because a toe point is out of reach.
+func (s *Spider) LoadShare() [4]float64 {
+	var legs [4]LegPosition
+	var feet [4]Point3D
+	n := 0
+	stance := s.stance()
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if stance[leg] {
+			legs[n] = leg
+			feet[n] = s.feet[leg].Add(s.legs[leg].origin)
+			n++
+		}
+	}
+	var shares [4]float64
+	for i, share := range LoadShares(feet[:n], s.CenterOfMass()) {
+		if i < n {
+			shares[legs[i]] = share
+		}
+	}
+	return shares
+}
+
+// sagOffsets returns the offset to add to each joint angle to compensate for sag, for the current stance and load.
+// If the load can't be worked out, it returns no offsets rather than spreading NaNs to every joint.
+func (s *Spider) sagOffsets() [4][3]float64 {
+	var offsets [4][3]float64
+	if s.sag == (SagModel{}) {
+		return offsets
+	}
+	shares := s.LoadShare()
+	for _, share := range shares {
+		if math.IsNaN(share) || math.IsInf(share, 0) {
+			return offsets
+		}
+	}
+	for leg, share := range shares {
+		offsets[leg][CoxaFemur] = share * s.sag.CoxaFemur
+		offsets[leg][FemurTibia] = share * s.sag.FemurTibia
+	}
+	return offsets
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestLoadShares(t *testing.T) {
+	square := []Point3D{{X: -50, Y: -50}, {X: 50, Y: -50}, {X: 50, Y: 50}, {X: -50, Y: 50}}
+	tests := []struct {
+		name string
+		feet []Point3D
+		com  Point3D
+		want []float64
+	}{
+		{"one foot", square[:1], Point3D{}, []float64{1}},
+		{"two feet", square[:2], Point3D{X: 25}, []float64{0.25, 0.75}},
+		{"two feet, beyond the end", square[:2], Point3D{X: 80}, []float64{0, 1}},
+		// With three feet, the shares are the barycentric coordinates of the centre of mass.
+		{"three feet", square[:3], Point3D{X: 0, Y: -25}, []float64{0.5, 0.25, 0.25}},
+		{"four feet, centred", square, Point3D{}, []float64{0.25, 0.25, 0.25, 0.25}},
+		{"four feet, shifted right", square, Point3D{X: 25}, []float64{0.125, 0.375, 0.375, 0.125}},
+		{"collinear", []Point3D{{X: -10}, {}, {X: 10}}, Point3D{}, []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}},
+	}
+	for _, tt := range tests {
+		got := LoadShares(tt.feet, tt.com)
+		for i := range tt.want {
+			if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+				t.Errorf("%s: LoadShares() = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLoadSharesBalance(t *testing.T) {
+	feet := []Point3D{{X: -60, Y: -40}, {X: 55, Y: -50}, {X: 45, Y: 60}, {X: -50, Y: 45}}
+	com := Point3D{X: 7, Y: -12}
+	shares := LoadShares(feet, com)
+	var total float64
+	var moment Point3D
+	for i, w := range shares {
+		if w < 0 {
+			t.Errorf("share %d = %v, want it to be non-negative", i, w)
+		}
+		total += w
+		moment = moment.Add(feet[i].Scale(w))
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("shares add up to %v, want 1", total)
+	}
+	if !approxEqualWithin(moment, com, 1e-9) {
+		t.Errorf("shares balance at %v, want %v", moment, com)
+	}
+}
+
+func TestSagCompensation(t *testing.T) {
+	model := SagModel{CoxaFemur: 0.1, FemurTibia: -0.1}
+	frame := func(sag SagModel, lifted bool) map[byte]uint16 {
+		r := NewRecorder()
+		config := DefaultConfig()
+		config.Sag = sag
+		s := New(r, config)
+		if lifted {
+			if err := s.SetLegState(FrontLeft, Lifted); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.SendCommandsToServos(); err != nil {
+			t.Fatal(err)
+		}
+		return r.Frames[len(r.Frames)-1]
+	}
+	s := newTestSpider()
+	plain, four, three := frame(SagModel{}, false), frame(model, false), frame(model, true)
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		p, f := legPulses(s, plain, leg), legPulses(s, four, leg)
+		if p[BodyCoxa] != f[BodyCoxa] {
+			t.Errorf("leg %d body-coxa pulse moved from %d to %d", leg, p[BodyCoxa], f[BodyCoxa])
+		}
+		if p[CoxaFemur] == f[CoxaFemur] || p[FemurTibia] == f[FemurTibia] {
+			t.Errorf("leg %d pulses = %v with sag compensation, want them to differ from %v", leg, f, p)
+		}
+	}
+	// A lifted leg carries no load, so it gets no offset, and the others carry more.
+	if p, l := legPulses(s, plain, FrontLeft), legPulses(s, three, FrontLeft); p != l {
+		t.Errorf("lifted leg pulses = %v, want %v", l, p)
+	}
+	offset := func(frame map[byte]uint16, leg LegPosition) int {
+		return int(legPulses(s, frame, leg)[CoxaFemur]) - int(legPulses(s, plain, leg)[CoxaFemur])
+	}
+	// With the centre of mass on the diagonal, the two legs either side of the lifted one take the load between them.
+	if four, three := offset(four, FrontRight), offset(three, FrontRight); abs(three) <= abs(four) {
+		t.Errorf("neighbouring leg offset is %d with three feet down, want more than %d", three, four)
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func TestSagCompensationUnreachableFoot(t *testing.T) {
+	config := DefaultConfig()
+	config.Sag = SagModel{CoxaFemur: 0.1, FemurTibia: -0.1}
+	s := New(NewRecorder(), config)
+	s.SetToePoint(BackLeft, Point3D{Z: -500})
+	if got := s.sagOffsets(); got != [4][3]float64{} {
+		t.Errorf("sagOffsets() with an unreachable foot = %v, want none", got)
+	}
+	var fe *FrameError
+	if err := s.SendCommandsToServos(); !errors.As(err, &fe) {
+		t.Fatalf("SendCommandsToServos() = %v, want a *FrameError", err)
+	}
+	for _, j := range fe.Joints {
+		if j.Leg != BackLeft {
+			t.Errorf("SendCommandsToServos() reported %v, want only the back left leg", j)
+		}
+	}
+}
+
+func TestSagOffsetsDontAllocate(t *testing.T) {
+	config := DefaultConfig()
+	config.Sag = SagModel{CoxaFemur: 0.1, FemurTibia: -0.1}
+	s := New(NewRecorder(), config)
+	if n := testing.AllocsPerRun(10, func() { s.sagOffsets() }); n != 0 {
+		t.Errorf("sagOffsets() made %v allocations, want 0", n)
+	}
+}
@@ -22,6 +22,21 @@ type Servo struct {
 	maxVal        uint16
 	zeroDegMicros int16
 	reversed      bool
+	// Compensation for the servo's deadband and gear backlash, in microseconds.
+	deadband uint16
+	backlash uint16
+}
+
+// NewServo returns a servo on the given pin, limited to [minVal, maxVal] microseconds.
+// zeroDegMicros is the (possibly out of range) pulse width that corresponds to zero degrees.
+func NewServo(pin byte, minVal, maxVal uint16, zeroDegMicros int16, reversed bool) Servo {
+	return Servo{
+		pin:           pin,
+		minVal:        minVal,
+		maxVal:        maxVal,
+		zeroDegMicros: zeroDegMicros,
+		reversed:      reversed,
+	}
 }
 
 func (s Servo) Pin() byte {
@@ -29,8 +44,7 @@ func (s Servo) Pin() byte {
 }
 
 func (s *Servo) RadiansToMicros(rad float64) uint16 {
-	deg := rad / math.Pi * 180
-	return s.DegreesToMicros(int16(math.Round(deg)))
+	return s.DegreesToMicros(radiansToDegrees(rad))
 }
 
 func (s *Servo) DegreesToMicros(deg int16) uint16 {
@@ -46,3 +60,41 @@ func (s *Servo) DegreesToMicros(deg int16) uint16 {
 	}
 	return micros
 }
+
+// SetCompensation sets the servo's deadband and gear backlash, in microseconds. Both are zero by default.
+// The servo stops up to half its deadband short of a target, and its output lags by the backlash after a reversal,
+// so both are compensated for by overshooting in the direction the servo is moving.
+func (s *Servo) SetCompensation(deadband, backlash uint16) {
+	s.deadband = deadband
+	s.backlash = backlash
+}
+
+// CompensatedMicros offsets a pulse width in the direction of approach, which is positive if the pulse width has been
+// increasing, negative if it has been decreasing, and zero if unknown. The result is clamped to the servo's range.
+func (s *Servo) CompensatedMicros(micros uint16, approach int) uint16 {
+	offset := (int(s.deadband) + int(s.backlash)) / 2
+	m := int(micros)
+	switch {
+	case approach > 0:
+		m += offset
+	case approach < 0:
+		m -= offset
+	}
+	if m > int(s.maxVal) {
+		return s.maxVal
+	}
+	if m < int(s.minVal) {
+		return s.minVal
+	}
+	return uint16(m)
+}
+
+// InRange reports whether the angle can be reached without being clamped to the servo's limits.
+func (s *Servo) InRange(rad float64) bool {
+	deg := math.Round(rad / math.Pi * 180)
+	if s.reversed {
+		deg = -deg
+	}
+	micros := deg*100/9 + float64(s.zeroDegMicros)
+	return micros >= float64(s.minVal) && micros <= float64(s.maxVal)
+}
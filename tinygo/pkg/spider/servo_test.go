@@ -63,3 +63,33 @@ func TestDegreesToMicros(t *testing.T) {
 		}
 	}
 }
+
+func TestCompensatedMicros(t *testing.T) {
+	s := Servo{
+		minVal:   1000,
+		maxVal:   2000,
+		deadband: 10,
+		backlash: 20,
+	}
+	tests := []struct {
+		micros   uint16
+		approach int
+		want     uint16
+	}{
+		{1500, 0, 1500},
+		{1500, 1, 1515},
+		{1500, -1, 1485},
+		{1995, 1, 2000},
+		{1005, -1, 1000},
+	}
+	for _, tt := range tests {
+		if got := s.CompensatedMicros(tt.micros, tt.approach); got != tt.want {
+			t.Errorf("s.CompensatedMicros(%d, %d) = %d, want %d", tt.micros, tt.approach, got, tt.want)
+		}
+	}
+
+	s.SetCompensation(0, 0)
+	if got := s.CompensatedMicros(1500, 1); got != 1500 {
+		t.Errorf("s.CompensatedMicros(1500, 1) = %d with no compensation, want 1500", got)
+	}
+}
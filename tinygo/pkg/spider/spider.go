@@ -15,149 +15,376 @@
 package spider
 
 import (
+	"fmt"
+	"math"
+	"time"
+
 	"github.com/timboldt/spiderbot/pkg/pca9685"
 )
 
 type Spider struct {
-	pwm    pca9685.Device
+	act    JointActuator
 	servos [12]Servo
 	legs   [4]Leg
+	// Toe points in the world frame, relative to each leg's canonical zero toe point, i.e. where the feet are planted.
+	feet      [4]Point3D
+	body      BodyPose
+	walk      walker
+	move      *move
+	queue     []queuedMove
+	mass      MassModel
+	minMargin float64
+	faults    FaultPolicy
+	// Status of the last frame, the number of consecutive skipped frames, and whether they tripped a safe stop.
+	frame    FrameStatus
+	failures int
+	stopped  bool
+	// Legs whose servos are turned off.
+	unpowered  [4]bool
+	odometry   Odometry
+	correction OdometryCorrection
+	// Why the last queued primitive was abandoned, if it was.
+	queueErr error
+	// Whether ApplyGait has moved the feet since the last ResetGait.
+	gaitApplied      bool
+	rejectCollisions bool
+	// The last uncompensated pulse sent to each servo, or 0 if none, and the direction it was moving in.
+	lastMicros [12]uint16
+	approach   [12]int
+	sag        SagModel
 }
 
-var (
-	theSpider Spider
-)
+// Config holds the per-robot settings used by New.
+type Config struct {
+	// Servo calibration, indexed by leg position and joint.
+	Servos [12]Servo
+	Walk   WalkConfig
+	Mass   MassModel
+	// Minimum stability margin required to lift a leg.
+	MinStabilityMargin float64
+	Faults             FaultPolicy
+	// Whether SendCommandsToServos refuses frames which would cause a collision.
+	RejectCollisions bool
+	// Offsets which stop the stance legs sagging under load, so that the body height doesn't depend on the gait.
+	Sag SagModel
+}
 
-// Init initializes the Spider instance, which is a simple singleton.
-func Init(pwm pca9685.Device) *Spider {
-	theSpider.pwm = pwm
-	theSpider.initServos()
+// DefaultConfig returns the calibration for the original robot.
+func DefaultConfig() Config {
+	return Config{
+		Servos: defaultServos,
+		Walk:   DefaultWalkConfig,
+		Mass:   DefaultMassModel,
+		Faults: DefaultFaultPolicy,
+	}
+}
+
+// New returns a Spider standing at the canonical zero pose.
+// Each call returns an independent instance.
+func New(act JointActuator, config Config) *Spider {
+	s := &Spider{
+		act:              act,
+		servos:           config.Servos,
+		walk:             walker{config: config.Walk},
+		mass:             config.Mass,
+		minMargin:        config.MinStabilityMargin,
+		faults:           config.Faults,
+		rejectCollisions: config.RejectCollisions,
+		sag:              config.Sag,
+	}
 	for i := 0; i < 4; i++ {
-		theSpider.legs[i].init(LegPosition(i))
+		s.legs[i].init(LegPosition(i))
 	}
-	return &theSpider
+	s.updateLegs()
+	return s
+}
+
+// Init returns a new Spider with the default configuration, driving a PCA9685 board.
+func Init(pwm pca9685.Device) *Spider {
+	return New(NewPCA9685Actuator(pwm), DefaultConfig())
 }
 
 func servoId(pos LegPosition, joint Joint) uint8 {
 	return uint8(pos)*3 + uint8(joint)
 }
 
-func (s *Spider) initServos() {
-	s.servos = [12]Servo{
-		// FR BC
-		{
-			pin:           0,
-			minVal:        1500,
-			maxVal:        2500,
-			zeroDegMicros: 1700,
-			reversed:      false,
-		},
-		// FR CF
-		{
-			pin:           1,
-			minVal:        1200,
-			maxVal:        2600,
-			zeroDegMicros: 2111,
-			reversed:      false,
-		},
-		// FR FT
-		{
-			pin:           2,
-			minVal:        1400,
-			maxVal:        2500,
-			zeroDegMicros: 900,
-			reversed:      false,
-		},
-		// FL BC
-		{
-			pin:           3,
-			minVal:        700,
-			maxVal:        1700,
-			zeroDegMicros: -400,
-			reversed:      false,
-		},
-		// FL CF
-		{
-			pin:           4,
-			minVal:        500,
-			maxVal:        1900,
-			zeroDegMicros: 1045,
-			reversed:      true,
-		},
-		// FL FT
-		{
-			pin:           5,
-			minVal:        1300,
-			maxVal:        2400,
-			zeroDegMicros: 2800,
-			reversed:      true,
-		},
-		// BR BC
-		{
-			pin:           6,
-			minVal:        700,
-			maxVal:        1700,
-			zeroDegMicros: 1800,
-			reversed:      false,
-		},
-		// BR CF
-		{
-			pin:           7,
-			minVal:        700,
-			maxVal:        2100,
-			zeroDegMicros: 1189,
-			reversed:      true,
-		},
-		// BR FT
-		{
-			pin:           8,
-			minVal:        1500,
-			maxVal:        2500,
-			zeroDegMicros: 3100,
-			reversed:      true,
-		},
-		// BL BC
-		{
-			pin:           9,
-			minVal:        1400,
-			maxVal:        2400,
-			zeroDegMicros: 3500,
-			reversed:      false,
-		},
-		// BL CF
-		{
-			pin:           10,
-			minVal:        1000,
-			maxVal:        2200,
-			zeroDegMicros: 1600,
-			reversed:      false,
-		},
-		// BL FT
-		{
-			pin:           11,
-			minVal:        1100,
-			maxVal:        2200,
-			zeroDegMicros: 600,
-			reversed:      false,
-		},
-	}
-}
-
-func (s *Spider) SendCommandsToServos() {
-	// fmt.Println("Moving!")
+var defaultServos = [12]Servo{
+	// FR BC
+	{
+		pin:           0,
+		minVal:        1500,
+		maxVal:        2500,
+		zeroDegMicros: 1700,
+		reversed:      false,
+	},
+	// FR CF
+	{
+		pin:           1,
+		minVal:        1200,
+		maxVal:        2600,
+		zeroDegMicros: 2111,
+		reversed:      false,
+	},
+	// FR FT
+	{
+		pin:           2,
+		minVal:        1400,
+		maxVal:        2500,
+		zeroDegMicros: 900,
+		reversed:      false,
+	},
+	// FL BC
+	{
+		pin:           3,
+		minVal:        700,
+		maxVal:        1700,
+		zeroDegMicros: -400,
+		reversed:      false,
+	},
+	// FL CF
+	{
+		pin:           4,
+		minVal:        500,
+		maxVal:        1900,
+		zeroDegMicros: 1045,
+		reversed:      true,
+	},
+	// FL FT
+	{
+		pin:           5,
+		minVal:        1300,
+		maxVal:        2400,
+		zeroDegMicros: 2800,
+		reversed:      true,
+	},
+	// BR BC
+	{
+		pin:           6,
+		minVal:        700,
+		maxVal:        1700,
+		zeroDegMicros: 1800,
+		reversed:      false,
+	},
+	// BR CF
+	{
+		pin:           7,
+		minVal:        700,
+		maxVal:        2100,
+		zeroDegMicros: 1189,
+		reversed:      true,
+	},
+	// BR FT
+	{
+		pin:           8,
+		minVal:        1500,
+		maxVal:        2500,
+		zeroDegMicros: 3100,
+		reversed:      true,
+	},
+	// BL BC
+	{
+		pin:           9,
+		minVal:        1400,
+		maxVal:        2400,
+		zeroDegMicros: 3500,
+		reversed:      false,
+	},
+	// BL CF
+	{
+		pin:           10,
+		minVal:        1000,
+		maxVal:        2200,
+		zeroDegMicros: 1600,
+		reversed:      false,
+	},
+	// BL FT
+	{
+		pin:           11,
+		minVal:        1100,
+		maxVal:        2200,
+		zeroDegMicros: 600,
+		reversed:      false,
+	},
+}
+
+// SendCommandsToServos sends the current joint angles to the actuator, following the fault policy if it fails.
+// The returned error is a *FrameError naming the joints that failed, were clamped to their servo's range or had no
+// valid angle, ErrSafeStop once the Spider has stopped, or ErrCollision if collision checking rejected the frame.
+func (s *Spider) SendCommandsToServos() error {
+	if s.stopped {
+		s.frame = FrameStatus{Err: ErrSafeStop}
+		return ErrSafeStop
+	}
+	if s.rejectCollisions {
+		if c := s.CheckCollisions(); len(c) > 0 {
+			err := fmt.Errorf("%w: %v", ErrCollision, c)
+			s.frame = FrameStatus{Err: err}
+			return err
+		}
+	}
+
+	// Work out the whole frame before touching the actuator, so that a bad angle doesn't leave it half sent.
+	var angles [12]float64
+	var invalid []JointError
+	sag := s.sagOffsets()
 	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
 		bc, cf, ft := s.legs[leg].JointAngles()
-		// fmt.Printf("Leg: %d Servo: %d Angle: %f usec: %d\n", leg, servoId(leg, BodyCoxa), bc, s.servos[servoId(leg, BodyCoxa)].RadiansToMicros(bc))
-		// fmt.Printf("Leg: %d Servo: %d Angle: %f usec: %d\n", leg, servoId(leg, CoxaFemur), cf, s.servos[servoId(leg, CoxaFemur)].RadiansToMicros(cf))
-		// fmt.Printf("Leg: %d Servo: %d Angle: %f usec: %d\n", leg, servoId(leg, FemurTibia), ft, s.servos[servoId(leg, FemurTibia)].RadiansToMicros(ft))
-		s.pwm.SetPin(servoId(leg, BodyCoxa), s.servos[servoId(leg, BodyCoxa)].RadiansToMicros(bc))
-		s.pwm.SetPin(servoId(leg, CoxaFemur), s.servos[servoId(leg, CoxaFemur)].RadiansToMicros(cf))
-		s.pwm.SetPin(servoId(leg, FemurTibia), s.servos[servoId(leg, FemurTibia)].RadiansToMicros(ft))
+		for joint, rad := range [3]float64{bc, cf, ft} {
+			rad += sag[leg][joint]
+			if !s.unpowered[leg] && (math.IsNaN(rad) || math.IsInf(rad, 0)) {
+				invalid = append(invalid, JointError{Leg: leg, Joint: Joint(joint), Err: ErrInvalidAngle})
+			}
+			angles[servoId(leg, Joint(joint))] = rad
+		}
+	}
+	if len(invalid) > 0 {
+		err := &FrameError{Joints: invalid}
+		s.frame = FrameStatus{Err: err}
+		return err
+	}
+
+	var pulses [12]uint16
+	var inRange [12]bool
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		for joint := BodyCoxa; joint <= FemurTibia; joint++ {
+			id := servoId(leg, joint)
+			rad := angles[id]
+			if s.unpowered[leg] {
+				// A pulse of 0 turns the servo off, and it may be moved by hand, so its direction is forgotten.
+				inRange[id] = true
+				s.approach[id] = 0
+				s.lastMicros[id] = 0
+				continue
+			}
+			pulses[id] = s.servoPulse(id, rad)
+			inRange[id] = s.servos[id].InRange(rad)
+		}
 	}
+
+	s.frame = FrameStatus{}
+	var err error
+	for s.frame.Attempts <= s.faults.Retries {
+		s.frame.Attempts++
+		err = s.sendFrame(&pulses, &inRange)
+		if err == nil || !err.(*FrameError).actuatorFailed() {
+			s.frame.Delivered = true
+			break
+		}
+	}
+	s.frame.Err = err
+
+	if s.frame.Delivered {
+		s.failures = 0
+		return err
+	}
+	s.failures++
+	if s.faults.TripAfter > 0 && s.failures >= s.faults.TripAfter {
+		s.safeStop()
+	}
+	return err
+}
+
+// servoPulse converts a joint angle to a pulse, tracking the direction each servo is moving in so that its
+// deadband and backlash can be compensated for. The direction only changes when the pulse does.
+func (s *Spider) servoPulse(id uint8, rad float64) uint16 {
+	servo := &s.servos[id]
+	micros := servo.RadiansToMicros(rad)
+	if last := s.lastMicros[id]; last != 0 {
+		switch {
+		case micros > last:
+			s.approach[id] = 1
+		case micros < last:
+			s.approach[id] = -1
+		}
+	}
+	s.lastMicros[id] = micros
+	return servo.CompensatedMicros(micros, s.approach[id])
 }
 
 func (s *Spider) SetAll(pt Point3D) {
 	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
-		s.legs[leg].toePt = pt
+		s.feet[leg] = pt
 	}
+	s.updateLegs()
+}
+
+// SetToePoint sets the world frame toe point of a single leg.
+func (s *Spider) SetToePoint(leg LegPosition, pt Point3D) {
+	s.feet[leg] = pt
+	s.updateLegs()
+}
+
+// ToePoint returns the world frame toe point of a leg.
+func (s *Spider) ToePoint(leg LegPosition) Point3D {
+	return s.feet[leg]
+}
+
+// SetBodyPose moves the body while keeping the feet planted.
+func (s *Spider) SetBodyPose(translation Point3D, roll, pitch, yaw float64) {
+	s.body = BodyPose{
+		Translation: translation,
+		Roll:        roll,
+		Pitch:       pitch,
+		Yaw:         yaw,
+	}
+	s.updateLegs()
+}
+
+func (s *Spider) BodyPose() BodyPose {
+	return s.body
+}
+
+// Update advances any move or walk in progress by the given amount of time.
+func (s *Spider) Update(dt time.Duration) {
+	if s.move == nil {
+		s.startQueuedMove()
+	}
+	switch {
+	case s.move != nil:
+		s.updateMove(dt)
+	case s.walk.active:
+		s.updateWalk(dt)
+	}
+}
+
+// updateLegs recomputes each leg's hip-relative toe point from the world frame toe points and the body pose.
+func (s *Spider) updateLegs() {
+	body := s.body.transform()
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		origin := s.legs[leg].origin
+		s.legs[leg].SetToePoint(body.toBody(s.feet[leg].Add(origin)).Sub(origin))
+	}
+}
+
+// ApplyGait moves the toes to the gait's current toe points, and puts each leg into swing or stance to match. It
+// should be called after each Update of the gait, with the same dt, so that the odometry follows the feet which stayed
+// on the ground. Lifted, parked and faulted legs don't take part in the gait.
+func (s *Spider) ApplyGait(g Gait, dt time.Duration) {
+	var planted stanceFeet
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		st := s.legs[leg].state
+		if st != Stance && st != Swing {
+			continue
+		}
+		before := s.feet[leg]
+		s.feet[leg] = g.ToePoint(leg)
+		if g.InSwing(leg) {
+			s.legs[leg].setState(Swing)
+			continue
+		}
+		s.legs[leg].setState(Stance)
+		// The first time a gait is applied the feet jump to it, which isn't the body moving.
+		if s.gaitApplied && st == Stance {
+			planted.add(before.Add(s.legs[leg].origin), s.feet[leg].Add(s.legs[leg].origin))
+		}
+	}
+	s.gaitApplied = true
+	s.updateOdometry(&planted, dt)
+	s.updateLegs()
+}
+
+// ResetGait makes the next ApplyGait start afresh, so that the feet jumping to the gait's toe points isn't counted
+// as the body moving. Call it before switching to a different gait. Starting a move does it too.
+func (s *Spider) ResetGait() {
+	s.gaitApplied = false
 }
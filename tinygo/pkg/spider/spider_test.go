@@ -0,0 +1,111 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"testing"
+
+	"github.com/timboldt/spiderbot/pkg/pca9685"
+)
+
+func TestNewReturnsIndependentInstances(t *testing.T) {
+	a := New(NewRecorder(), DefaultConfig())
+	b := New(NewRecorder(), DefaultConfig())
+	if a == b {
+		t.Fatal("New() returned the same instance twice")
+	}
+	a.SetAll(Point3D{X: 10, Y: 5, Z: -5})
+	a.SetVelocity(10, 0, 0)
+	if got := b.ToePoint(FrontRight); got != (Point3D{}) {
+		t.Errorf("ToePoint() on second instance = %v, want zero", got)
+	}
+	if b.Walking() {
+		t.Error("Walking() on second instance = true, want false")
+	}
+}
+
+func TestInitDoesNotReconfigureEarlierInstances(t *testing.T) {
+	a := Init(pca9685.Device{})
+	a.SetAll(Point3D{Z: 10})
+	Init(pca9685.Device{})
+	if got, want := a.ToePoint(BackLeft), (Point3D{Z: 10}); got != want {
+		t.Errorf("ToePoint() after second Init() = %v, want %v", got, want)
+	}
+}
+
+func TestNewUsesConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.Servos[0] = NewServo(0, 1000, 2000, 1500, true)
+	config.MinStabilityMargin = 7
+	s := New(NewRecorder(), config)
+	if got := s.servos[0].RadiansToMicros(0); got != 1500 {
+		t.Errorf("RadiansToMicros(0) on configured servo = %d, want 1500", got)
+	}
+	if s.minMargin != 7 {
+		t.Errorf("minMargin = %v, want 7", s.minMargin)
+	}
+	if d := New(NewRecorder(), DefaultConfig()); d.servos[0] == s.servos[0] {
+		t.Error("configuring one instance changed the default servo calibration")
+	}
+}
+
+func TestServoCompensation(t *testing.T) {
+	r := NewRecorder()
+	config := DefaultConfig()
+	config.Servos[servoId(FrontRight, BodyCoxa)].SetCompensation(10, 20)
+	s := New(r, config)
+	servo := s.servos[servoId(FrontRight, BodyCoxa)]
+
+	tests := []struct {
+		toeX   float64
+		offset int
+	}{
+		// The first frame has nothing to compare against.
+		{0, 0},
+		// Moving the toe in +X swings the coxa clockwise, which decreases the pulse.
+		{10, -15},
+		{20, -15},
+		// Holding still keeps the last direction.
+		{20, -15},
+		{0, 15},
+		{5, -15},
+	}
+	for _, tt := range tests {
+		s.SetToePoint(FrontRight, Point3D{X: tt.toeX})
+		s.SendCommandsToServos()
+		bc, _, _ := s.legs[FrontRight].JointAngles()
+		want := uint16(int(servo.RadiansToMicros(bc)) + tt.offset)
+		if got := r.Pulse(servo.Pin()); got != want {
+			t.Errorf("toe X = %v: pulse = %d, want %d", tt.toeX, got, want)
+		}
+	}
+
+	// Servos without compensation are unaffected.
+	other := s.servos[servoId(FrontRight, CoxaFemur)]
+	_, cf, _ := s.legs[FrontRight].JointAngles()
+	if got, want := r.Pulse(other.Pin()), other.RadiansToMicros(cf); got != want {
+		t.Errorf("uncompensated servo pulse = %d, want %d", got, want)
+	}
+
+	// Turning a servo off forgets its direction.
+	s.unpowered[FrontRight] = true
+	s.SendCommandsToServos()
+	s.unpowered[FrontRight] = false
+	s.SendCommandsToServos()
+	bc, _, _ := s.legs[FrontRight].JointAngles()
+	if got, want := r.Pulse(servo.Pin()), servo.RadiansToMicros(bc); got != want {
+		t.Errorf("pulse after power cycle = %d, want uncompensated %d", got, want)
+	}
+}
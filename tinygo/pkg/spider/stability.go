@@ -0,0 +1,200 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+var ErrUnstable = errors.New("spider: lifting leg would tip the robot over")
+
+// MassModel gives the mass of each part of the robot, in any consistent unit.
+// Leg segment masses are treated as being at the middle of the segment, and include the servo which drives them.
+type MassModel struct {
+	Body  float64
+	Coxa  float64
+	Femur float64
+	Tibia float64
+}
+
+// Approximate masses in grams.
+var DefaultMassModel = MassModel{
+	Body:  250,
+	Coxa:  12,
+	Femur: 12,
+	Tibia: 15,
+}
+
+// SupportPolygon returns the convex hull of the given feet, projected onto the ground, in counter-clockwise order.
+func SupportPolygon(feet []Point3D) []Point3D {
+	pts := make([]Point3D, len(feet))
+	for i, pt := range feet {
+		pts[i] = Point3D{X: pt.X, Y: pt.Y}
+	}
+	if len(pts) < 3 {
+		return pts
+	}
+	sort.Slice(pts, func(i, j int) bool {
+		if pts[i].X != pts[j].X {
+			return pts[i].X < pts[j].X
+		}
+		return pts[i].Y < pts[j].Y
+	})
+
+	// Andrew's monotone chain: build the lower hull left to right, then the upper hull right to left.
+	hull := make([]Point3D, 0, 2*len(pts))
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for i := range pts {
+			pt := pts[i]
+			if pass == 1 {
+				pt = pts[len(pts)-1-i]
+			}
+			for len(hull) >= start+2 && cross2D(hull[len(hull)-2], hull[len(hull)-1], pt) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, pt)
+		}
+		// The last point is the first point of the other half.
+		hull = hull[:len(hull)-1]
+	}
+	return hull
+}
+
+// StabilityMargin returns the distance from the centre of mass, projected onto the ground, to the nearest edge of the
+// support polygon. It is positive inside the polygon and negative outside.
+func StabilityMargin(polygon []Point3D, com Point3D) float64 {
+	com.Z = 0
+	switch len(polygon) {
+	case 0:
+		return math.Inf(-1)
+	case 1:
+		d := com.Sub(polygon[0])
+		return -math.Sqrt(d.X*d.X + d.Y*d.Y)
+	case 2:
+		return -distanceToSegment(com, polygon[0], polygon[1])
+	}
+	margin := math.Inf(1)
+	inside := true
+	for i := range polygon {
+		a, b := polygon[i], polygon[(i+1)%len(polygon)]
+		if cross2D(a, b, com) < 0 {
+			inside = false
+		}
+		margin = math.Min(margin, distanceToSegment(com, a, b))
+	}
+	if !inside {
+		return -margin
+	}
+	return margin
+}
+
+// SetMassModel changes the mass model used to find the centre of mass.
+func (s *Spider) SetMassModel(m MassModel) {
+	s.mass = m
+}
+
+// SetMinStabilityMargin sets how far inside the support polygon the centre of mass must stay when a leg is lifted.
+func (s *Spider) SetMinStabilityMargin(margin float64) {
+	s.minMargin = margin
+}
+
+// CenterOfMass returns the centre of mass in the world frame, using the current joint angles.
+func (s *Spider) CenterOfMass() Point3D {
+	m := s.mass
+	total := m.Body
+	body := s.body.transform()
+	sum := body.toWorld(Point3D{}).Scale(m.Body)
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		l := &s.legs[leg]
+		bc, cf, ft := l.JointAngles()
+		pts := l.jointPoints([3]float64{bc, cf, ft})
+		for i, mass := range [3]float64{m.Coxa, m.Femur, m.Tibia} {
+			mid := pts[i].Add(pts[i+1]).Scale(0.5).Add(l.origin)
+			sum = sum.Add(body.toWorld(mid).Scale(mass))
+			total += mass
+		}
+	}
+	return sum.Scale(1 / total)
+}
+
+// StabilityMargin returns the current stability margin, using the feet which are on the ground.
+func (s *Spider) StabilityMargin() float64 {
+	return s.marginWith(s.stance())
+}
+
+// LiftMargin returns what the stability margin would be if the given leg were lifted.
+func (s *Spider) LiftMargin(leg LegPosition) float64 {
+	stance := s.stance()
+	stance[leg] = false
+	return s.marginWith(stance)
+}
+
+// LiftLeg raises a foot by the given height, unless doing so would leave the centre of mass too close to the edge
+// of the support polygon, in which case it returns ErrUnstable and leaves the foot where it is.
+// The margin has to be greater than the minimum by more than rounding error: with the default minimum of zero, a
+// margin of zero is the tipping point. In the neutral stance the centre of mass is on the edge of every support
+// triangle, so the body has to lean away from the leg first.
+func (s *Spider) LiftLeg(leg LegPosition, height float64) error {
+	// A NaN margin, from a pose which can't be reached, counts as unstable.
+	if m := s.LiftMargin(leg); s.legs[leg].state == Stance && !(m > s.minMargin+kinematicsEpsilon) {
+		return ErrUnstable
+	}
+	if err := s.SetLegState(leg, Lifted); err != nil {
+		return err
+	}
+	s.feet[leg].Z += height
+	s.updateLegs()
+	return nil
+}
+
+// LowerLeg lowers a foot by the given height, and counts it as being on the ground again if it was lifted.
+func (s *Spider) LowerLeg(leg LegPosition, height float64) {
+	if s.legs[leg].state == Lifted {
+		s.legs[leg].setState(Stance)
+	}
+	s.feet[leg].Z -= height
+	s.updateLegs()
+}
+
+// marginWith returns the stability margin if only the given feet were on the ground.
+func (s *Spider) marginWith(stance [4]bool) float64 {
+	var feet []Point3D
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if stance[leg] {
+			feet = append(feet, s.feet[leg].Add(s.legs[leg].origin))
+		}
+	}
+	return StabilityMargin(SupportPolygon(feet), s.CenterOfMass())
+}
+
+// cross2D returns the Z component of (b-a) x (c-a), which is positive if a, b, c turn counter-clockwise.
+func cross2D(a, b, c Point3D) float64 {
+	return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
+
+// distanceToSegment returns the distance from p to the line segment ab, ignoring Z.
+func distanceToSegment(p, a, b Point3D) float64 {
+	ab := Point3D{X: b.X - a.X, Y: b.Y - a.Y}
+	ap := Point3D{X: p.X - a.X, Y: p.Y - a.Y}
+	t := 0.0
+	if lenSq := ab.X*ab.X + ab.Y*ab.Y; lenSq > 0 {
+		t = math.Max(0, math.Min(1, (ap.X*ab.X+ap.Y*ab.Y)/lenSq))
+	}
+	d := ap.Sub(ab.Scale(t))
+	return math.Sqrt(d.X*d.X + d.Y*d.Y)
+}
@@ -0,0 +1,197 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSupportPolygon(t *testing.T) {
+	feet := []Point3D{
+		{X: 1, Y: 1, Z: -3},
+		{X: -1, Y: 1},
+		{X: 0, Y: 0},
+		{X: -1, Y: -1},
+		{X: 1, Y: -1},
+	}
+	got := SupportPolygon(feet)
+	want := []Point3D{{X: -1, Y: -1}, {X: 1, Y: -1}, {X: 1, Y: 1}, {X: -1, Y: 1}}
+	if len(got) != len(want) {
+		t.Fatalf("SupportPolygon() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("SupportPolygon() = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestStabilityMargin(t *testing.T) {
+	square := SupportPolygon([]Point3D{{X: -1, Y: -1}, {X: 1, Y: -1}, {X: 1, Y: 1}, {X: -1, Y: 1}})
+	tests := []struct {
+		polygon []Point3D
+		com     Point3D
+		want    float64
+	}{
+		{square, Point3D{}, 1},
+		{square, Point3D{X: 0.5, Z: 10}, 0.5},
+		{square, Point3D{X: 3}, -2},
+		{square[:2], Point3D{Y: -1}, 0},
+		{square[:2], Point3D{}, -1},
+		{square[:1], Point3D{X: 2, Y: -1}, -3},
+	}
+	for _, tt := range tests {
+		if got := StabilityMargin(tt.polygon, tt.com); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("StabilityMargin(%v, %v) = %v, want %v", tt.polygon, tt.com, got, tt.want)
+		}
+	}
+}
+
+func TestCenterOfMassFollowsBody(t *testing.T) {
+	s := newTestSpider()
+	com := s.CenterOfMass()
+	if math.Abs(com.X) > kinematicsEpsilon || math.Abs(com.Y) > kinematicsEpsilon {
+		t.Errorf("CenterOfMass() = %v in the neutral stance, want it centred", com)
+	}
+	s.SetBodyPose(Point3D{X: 10, Y: 10}, 0, 0, 0)
+	com = s.CenterOfMass()
+	if com.X <= 5 || com.Y <= 5 {
+		t.Errorf("CenterOfMass() = %v after moving the body by (10, 10)", com)
+	}
+}
+
+func TestLiftLegGuard(t *testing.T) {
+	s := newTestSpider()
+	if m := s.StabilityMargin(); m <= 0 {
+		t.Errorf("StabilityMargin() = %v standing on four feet, want positive", m)
+	}
+
+	// Leaning towards the front right makes it safe to lift the back left leg, but not the front right one.
+	s.SetBodyPose(Point3D{X: 10, Y: 10}, 0, 0, 0)
+	if err := s.LiftLeg(FrontRight, 20); err != ErrUnstable {
+		t.Errorf("LiftLeg(FrontRight) = %v, want ErrUnstable", err)
+	}
+	if got := s.ToePoint(FrontRight); got.Z != 0 {
+		t.Errorf("LiftLeg(FrontRight) moved the foot to %v", got)
+	}
+	if err := s.LiftLeg(BackLeft, 20); err != nil {
+		t.Errorf("LiftLeg(BackLeft) = %v, want nil", err)
+	}
+	if got := s.ToePoint(BackLeft); got.Z != 20 {
+		t.Errorf("LiftLeg(BackLeft) moved the foot to %v", got)
+	}
+	if m := s.StabilityMargin(); m <= 0 {
+		t.Errorf("StabilityMargin() = %v with the back left leg lifted, want positive", m)
+	}
+	s.LowerLeg(BackLeft, 20)
+	if got := s.ToePoint(BackLeft); got.Z != 0 {
+		t.Errorf("LowerLeg(BackLeft) moved the foot to %v", got)
+	}
+}
+
+func TestWalkDelaysUnstableLifts(t *testing.T) {
+	s := newTestSpider()
+	cfg := DefaultWalkConfig
+	cfg.DelayUnstableLifts = true
+	s.SetWalkConfig(cfg)
+	// Without shifting the body, the walk pattern can't lift a leg with a healthy margin, so it never gets going.
+	s.SetMinStabilityMargin(5)
+	s.SetVelocity(0, 0, 0.1)
+	for i := 0; i < 100; i++ {
+		s.Update(10 * time.Millisecond)
+		if m := s.StabilityMargin(); m < 5 {
+			t.Fatalf("StabilityMargin() = %v after %d ticks", m, i)
+		}
+	}
+}
+
+func TestWalkLeansForHeldLifts(t *testing.T) {
+	s := newTestSpider()
+	cfg := DefaultWalkConfig
+	cfg.DelayUnstableLifts = true
+	s.SetWalkConfig(cfg)
+	s.SetMinStabilityMargin(5)
+	s.SetVelocity(0, 20, 0)
+	// The gait has to lean the body before each lift, and the feet mustn't slide out of reach while it waits.
+	swung := 0
+	for i := 0; i < 1000; i++ {
+		var before [4]LegState
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			before[leg] = s.LegState(leg)
+		}
+		s.Update(10 * time.Millisecond)
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if !s.ToeReachable(leg, s.ToePoint(leg)) {
+				t.Fatalf("leg %d is out of reach at %v after %d ticks", leg, s.ToePoint(leg), i)
+			}
+			// The margin is checked at lift-off; the stance feet have moved on by a tick since then.
+			if before[leg] == Stance && s.LegState(leg) == Swing {
+				swung++
+				if m := s.StabilityMargin(); m < 4.5 {
+					t.Errorf("leg %d lifted with StabilityMargin() = %v", leg, m)
+				}
+			}
+		}
+	}
+	if swung == 0 {
+		t.Error("no leg swung in 10s, want the body to lean until the lifts are safe")
+	}
+	if got := s.Odometry(); got.Y < 50 {
+		t.Errorf("Odometry() after 10s = %+v, want the spider to have walked forwards", got)
+	}
+}
+
+func TestLiftLegUnknownMargin(t *testing.T) {
+	s := newTestSpider()
+	// An unreachable foot makes the centre of mass, and so the margin, NaN.
+	s.SetToePoint(BackLeft, Point3D{Z: -500})
+	if err := s.LiftLeg(FrontRight, 20); err != ErrUnstable {
+		t.Errorf("LiftLeg() with a NaN stability margin = %v, want ErrUnstable", err)
+	}
+	if got := s.LegState(FrontRight); got != Stance {
+		t.Errorf("LegState() after a refused lift = %v, want stance", got)
+	}
+}
+
+func TestWalkHoldsLiftsWithUnknownMargin(t *testing.T) {
+	s := newTestSpider()
+	cfg := DefaultWalkConfig
+	cfg.DelayUnstableLifts = true
+	s.SetWalkConfig(cfg)
+	// An unreachable foot makes the centre of mass, and so the margin, NaN.
+	s.SetToePoint(BackLeft, Point3D{Z: -500})
+	s.SetVelocity(0, 20, 0)
+	for i := 0; i < 100; i++ {
+		s.Update(10 * time.Millisecond)
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if s.LegState(leg) == Swing {
+				t.Fatalf("leg %d lifted after %d ticks with a NaN stability margin", leg, i)
+			}
+		}
+	}
+}
+
+func TestLiftLegAtTippingPoint(t *testing.T) {
+	s := newTestSpider()
+	// In the neutral stance the centre of mass is on the edge of every support triangle.
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if err := s.LiftLeg(leg, 20); err != ErrUnstable {
+			t.Errorf("LiftLeg(%d) in the neutral stance = %v, want ErrUnstable", leg, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+)
+
+// SwingTrajectory returns the position of a foot in the air, given where it lifted off, where it will touch down,
+// how high to lift it, and the fraction of the swing completed.
+// Trajectories start and end with zero velocity, so that the foot doesn't scuff the ground.
+type SwingTrajectory func(from, to Point3D, height, u float64) Point3D
+
+// CycloidSwing moves the foot along a cycloid, which has the lowest peak acceleration of the smooth trajectories.
+func CycloidSwing(from, to Point3D, height, u float64) Point3D {
+	s := u - math.Sin(2*math.Pi*u)/(2*math.Pi)
+	pt := from.Add(to.Sub(from).Scale(s))
+	pt.Z += height * (1 - math.Cos(2*math.Pi*u)) / 2
+	return pt
+}
+
+// CubicBezierSwing moves the foot along a cubic Bezier curve with both control points raised above the ends.
+// The curve is eased in and out, since a cubic can't otherwise have zero velocity at its ends.
+func CubicBezierSwing(from, to Point3D, height, u float64) Point3D {
+	// A cubic reaches 3/4 of the way to its control points half way along.
+	lift := Point3D{Z: height * 4 / 3}
+	return bezier([]Point3D{from, from.Add(lift), to.Add(lift), to}, smoothstep(u))
+}
+
+// QuinticBezierSwing moves the foot along a quintic Bezier curve with doubled end points, so that it starts and
+// ends with zero velocity without any easing.
+func QuinticBezierSwing(from, to Point3D, height, u float64) Point3D {
+	// A quintic reaches 5/8 of the way to its middle control points half way along.
+	lift := Point3D{Z: height * 8 / 5}
+	return bezier([]Point3D{from, from, from.Add(lift), to.Add(lift), to, to}, u)
+}
+
+// RectangularSwing lifts the foot straight up, moves it across, then drops it straight down, pausing briefly between
+// each move. It gives the most ground clearance, at the cost of the highest accelerations.
+func RectangularSwing(from, to Point3D, height, u float64) Point3D {
+	switch {
+	case u < 1.0/3:
+		return from.Add(Point3D{Z: height * smoothstep(u*3)})
+	case u < 2.0/3:
+		pt := from.Add(to.Sub(from).Scale(smoothstep(u*3 - 1)))
+		pt.Z = from.Z + height
+		return pt
+	default:
+		pt := to
+		pt.Z = from.Z + height + (to.Z-from.Z-height)*smoothstep(u*3-2)
+		return pt
+	}
+}
+
+// smoothstep eases from 0 to 1 with zero slope at both ends.
+func smoothstep(u float64) float64 {
+	return u * u * (3 - 2*u)
+}
+
+// bezier evaluates a Bezier curve with De Casteljau's algorithm.
+func bezier(pts []Point3D, u float64) Point3D {
+	var work [6]Point3D
+	n := copy(work[:], pts)
+	for ; n > 1; n-- {
+		for i := 0; i < n-1; i++ {
+			work[i] = work[i].Add(work[i+1].Sub(work[i]).Scale(u))
+		}
+	}
+	return work[0]
+}
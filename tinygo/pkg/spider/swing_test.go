@@ -0,0 +1,92 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+)
+
+var swingTrajectories = []struct {
+	name  string
+	swing SwingTrajectory
+}{
+	{"cycloid", CycloidSwing},
+	{"cubic", CubicBezierSwing},
+	{"quintic", QuinticBezierSwing},
+	{"rectangular", RectangularSwing},
+}
+
+func TestSwingEndPoints(t *testing.T) {
+	from := Point3D{X: 1, Y: -15, Z: -5}
+	to := Point3D{X: -2, Y: 15, Z: -3}
+	for _, tt := range swingTrajectories {
+		if got := tt.swing(from, to, 20, 0); !approxEqual(got, from) {
+			t.Errorf("%s(0) = %v, want %v", tt.name, got, from)
+		}
+		if got := tt.swing(from, to, 20, 1); !approxEqual(got, to) {
+			t.Errorf("%s(1) = %v, want %v", tt.name, got, to)
+		}
+		// All of the trajectories clear the ground by the step height.
+		if got := tt.swing(from, to, 20, 0.5); got.Z < from.Z+19.9 {
+			t.Errorf("%s(0.5) = %v, want at least %v high", tt.name, got, from.Z+20)
+		}
+	}
+}
+
+func TestSwingContinuity(t *testing.T) {
+	from := Point3D{X: 1, Y: -15, Z: -5}
+	to := Point3D{X: -2, Y: 15, Z: -3}
+	const n = 1000
+	const h = 1.0 / n
+	for _, tt := range swingTrajectories {
+		velocity := func(u float64) Point3D {
+			return tt.swing(from, to, 20, u+h).Sub(tt.swing(from, to, 20, u)).Scale(1 / h)
+		}
+		// Zero velocity at lift-off and touchdown.
+		if v := velocity(0); math.Sqrt(v.X*v.X+v.Y*v.Y+v.Z*v.Z) > 1 {
+			t.Errorf("%s has velocity %v at lift-off", tt.name, v)
+		}
+		if v := velocity(1 - h); math.Sqrt(v.X*v.X+v.Y*v.Y+v.Z*v.Z) > 1 {
+			t.Errorf("%s has velocity %v at touchdown", tt.name, v)
+		}
+		// No jumps in position or velocity along the way.
+		prevPt, prevVel := tt.swing(from, to, 20, 0), velocity(0)
+		for i := 1; i < n; i++ {
+			u := float64(i) * h
+			pt, vel := tt.swing(from, to, 20, u), velocity(u)
+			if d := pt.Sub(prevPt); math.Sqrt(d.X*d.X+d.Y*d.Y+d.Z*d.Z) > 0.5 {
+				t.Errorf("%s jumped from %v to %v at %v", tt.name, prevPt, pt, u)
+			}
+			if d := vel.Sub(prevVel); math.Sqrt(d.X*d.X+d.Y*d.Y+d.Z*d.Z) > 2 {
+				t.Errorf("%s velocity jumped from %v to %v at %v", tt.name, prevVel, vel, u)
+			}
+			prevPt, prevVel = pt, vel
+		}
+	}
+}
+
+func TestGaitUsesSwingTrajectory(t *testing.T) {
+	params := testGaitParams
+	params.Swing = RectangularSwing
+	g := NewPhaseGait(params, TrotPattern)
+	// Half way through the lift, the rectangular trajectory has risen half the step height but not moved forwards.
+	g.phase = TrotPattern.DutyFactor + (1-TrotPattern.DutyFactor)/6
+	pt := g.ToePoint(FrontRight)
+	want := Point3D{Y: -params.StrideLength / 2, Z: TibiaLength - params.BodyHeight + params.StepHeight/2}
+	if !approxEqual(pt, want) {
+		t.Errorf("ToePoint() = %v, want %v", pt, want)
+	}
+}
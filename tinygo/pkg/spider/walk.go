@@ -0,0 +1,290 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"time"
+)
+
+// WalkConfig controls how the spider walks in response to SetVelocity.
+type WalkConfig struct {
+	// Gait.StrideLength is the longest stride any foot may take; faster commands are scaled down to fit.
+	Gait    GaitParams
+	Pattern GaitPattern
+	// Acceleration limits, in distance units per second squared and radians per second squared.
+	MaxAccel        float64
+	MaxAngularAccel float64
+	// If set, the gait pauses rather than lifting a leg while doing so would take the stability margin below the
+	// minimum. While it waits, the body leans towards the feet which will stay down until the lift is safe.
+	DelayUnstableLifts bool
+}
+
+var DefaultWalkConfig = WalkConfig{
+	Gait: GaitParams{
+		StrideLength: 40,
+		StepHeight:   15,
+		BodyHeight:   TibiaLength,
+		Period:       2 * time.Second,
+	},
+	Pattern:         WalkPattern,
+	MaxAccel:        40,
+	MaxAngularAccel: 1,
+}
+
+// How close to neutral the feet need to be for the spider to stop stepping.
+const neutralTolerance = 0.1
+
+// How fast the body leans over the remaining feet while a lift is held, in distance units per second.
+const liftShiftSpeed = 20.0
+
+// How fast feet left in the air by the gait are put down before a move, in distance units per second.
+const landingSpeed = 50.0
+
+type velocity struct {
+	x, y, omega float64
+}
+
+type walker struct {
+	config WalkConfig
+	// Whether Update is moving the feet.
+	active bool
+	// Fraction of the way through the gait cycle, in [0, 1).
+	phase float64
+	// Commanded velocity, and the current velocity after acceleration limiting.
+	cmd, vel  velocity
+	liftOff   [4]Point3D
+	touchdown [4]Point3D
+}
+
+// SetWalkConfig changes how the spider walks. It takes effect on the next Update.
+func (s *Spider) SetWalkConfig(config WalkConfig) {
+	s.walk.config = config
+}
+
+// SetVelocity commands the body to move with the given velocity, in distance units per second along X and Y
+// and radians per second counter-clockwise about the centre of the body.
+// Commands that would need a longer stride than the configured StrideLength are scaled down.
+// A zero velocity brings all feet back to the neutral stance, and then stops stepping.
+func (s *Spider) SetVelocity(vx, vy, omega float64) {
+	cmd := velocity{x: vx, y: vy, omega: omega}
+	stanceTime := s.walk.config.Pattern.DutyFactor * s.walk.config.Gait.Period.Seconds()
+	longest := 0.0
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		v := s.footVelocity(cmd, leg, s.neutralToePoint())
+		longest = math.Max(longest, math.Sqrt(v.X*v.X+v.Y*v.Y)*stanceTime)
+	}
+	if maxStride := s.walk.config.Gait.StrideLength; longest > maxStride {
+		k := maxStride / longest
+		cmd = velocity{x: cmd.x * k, y: cmd.y * k, omega: cmd.omega * k}
+	}
+	s.walk.cmd = cmd
+	s.walk.active = true
+	s.move = nil
+	s.queue = nil
+}
+
+// Velocity returns the current body velocity, which lags the commanded velocity because of the acceleration limits.
+func (s *Spider) Velocity() (float64, float64, float64) {
+	return s.walk.vel.x, s.walk.vel.y, s.walk.vel.omega
+}
+
+// Walking reports whether Update is currently moving the feet.
+func (s *Spider) Walking() bool {
+	return s.walk.active
+}
+
+func (s *Spider) updateWalk(dt time.Duration) {
+	w := &s.walk
+	cfg := w.config
+	sec := dt.Seconds()
+	period := cfg.Gait.Period.Seconds()
+	if period <= 0 {
+		return
+	}
+
+	prevVel := w.vel
+	w.vel = velocity{
+		x:     approach(w.vel.x, w.cmd.x, cfg.MaxAccel*sec),
+		y:     approach(w.vel.y, w.cmd.y, cfg.MaxAccel*sec),
+		omega: approach(w.vel.omega, w.cmd.omega, cfg.MaxAngularAccel*sec),
+	}
+	prevPhase := w.phase
+	w.phase += sec / period
+	w.phase -= math.Floor(w.phase)
+
+	duty := cfg.Pattern.DutyFactor
+	// Legs which are due to lift off, but are being held down for stability.
+	var hold [4]bool
+	paused := false
+	// How far the feet on the ground move while the gait is paused, to lean the body.
+	var shift Point3D
+	if cfg.DelayUnstableLifts {
+		var stance, lifting [4]bool
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			st := s.legs[leg].state
+			stance[leg] = (st == Stance || st == Swing) && w.legPhase(w.phase, leg) < duty
+			lifting[leg] = st == Stance && w.legPhase(w.phase, leg) >= duty
+		}
+		// An unreachable foot gives a NaN margin, which counts as unstable.
+		if m := s.marginWith(stance); lifting != [4]bool{} && !(m > s.minMargin+kinematicsEpsilon) {
+			// Freeze the whole gait, so that the feet in stance don't slide out of reach while the lift waits.
+			w.phase = prevPhase
+			w.vel = prevVel
+			hold = lifting
+			paused = true
+			shift = s.liftShift(stance, liftShiftSpeed*sec)
+		}
+	}
+	stanceTime := duty * period
+	neutral := s.neutralToePoint()
+	settled := w.vel == velocity{} && w.cmd == velocity{}
+	var planted stanceFeet
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		// Lifted, parked and faulted legs don't take part in the gait.
+		st := s.legs[leg].state
+		if st != Stance && st != Swing {
+			continue
+		}
+		legPhase := w.legPhase(w.phase, leg)
+		if legPhase < duty || hold[leg] {
+			// In stance, the foot moves backwards relative to the body.
+			if st == Swing {
+				// Finish the swing, which the last tick will have stopped just short of.
+				s.legs[leg].setState(Stance)
+				s.feet[leg] = w.touchdown[leg]
+			}
+			before := s.feet[leg]
+			if paused {
+				s.feet[leg] = s.feet[leg].Add(shift)
+			} else {
+				s.feet[leg] = s.feet[leg].Add(s.footVelocity(w.vel, leg, s.feet[leg]).Scale(sec))
+			}
+			if st == Stance {
+				planted.add(before.Add(s.legs[leg].origin), s.feet[leg].Add(s.legs[leg].origin))
+			}
+		} else {
+			// In swing, the foot heads for a point ahead of neutral, so that it passes through neutral half way through
+			// its next stance.
+			if st == Stance {
+				s.legs[leg].setState(Swing)
+				w.liftOff[leg] = s.feet[leg]
+			}
+			target := neutral.Sub(s.footVelocity(w.vel, leg, neutral).Scale(stanceTime / 2))
+			w.touchdown[leg] = w.liftOff[leg]
+			if d := target.Sub(w.liftOff[leg]); math.Sqrt(d.X*d.X+d.Y*d.Y+d.Z*d.Z) > neutralTolerance {
+				s.feet[leg] = cfg.Gait.swing(w.liftOff[leg], target, (legPhase-duty)/(1-duty))
+				w.touchdown[leg] = target
+			}
+			// Otherwise the foot is already where it needs to be, so it stays down.
+		}
+		d := s.feet[leg].Sub(neutral)
+		if math.Sqrt(d.X*d.X+d.Y*d.Y+d.Z*d.Z) > neutralTolerance {
+			settled = false
+		}
+	}
+	s.updateOdometry(&planted, dt)
+	if settled {
+		s.stopWalking()
+		// Every foot is back at neutral, so any that the gait still counts as swinging are already down.
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if s.legs[leg].state == Swing {
+				s.legs[leg].setState(Stance)
+			}
+		}
+	}
+	s.updateLegs()
+}
+
+// liftShift returns how far to move the feet on the ground, by no more than step, to bring the centre of mass towards
+// the centroid of the given feet. It is zero if the centre of mass is unknown.
+func (s *Spider) liftShift(stance [4]bool, step float64) Point3D {
+	var c Point3D
+	n := 0
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if stance[leg] {
+			c = c.Add(s.feet[leg].Add(s.legs[leg].origin))
+			n++
+		}
+	}
+	if n == 0 {
+		return Point3D{}
+	}
+	com := s.CenterOfMass()
+	// Moving the feet one way moves the body, and so the centre of mass, the other.
+	d := Point3D{X: com.X - c.X/float64(n), Y: com.Y - c.Y/float64(n)}
+	dist := math.Sqrt(d.X*d.X + d.Y*d.Y)
+	if !finite(d) || dist == 0 {
+		return Point3D{}
+	}
+	return d.Scale(math.Min(step, dist) / dist)
+}
+
+// stopWalking stops the gait. Any feet it had in the air stay in swing until a move puts them down.
+func (s *Spider) stopWalking() {
+	s.walk.active = false
+	s.walk.vel = velocity{}
+}
+
+// landingMove returns a move which puts down any feet the gait left in the air, straight down to the height they
+// lifted off from, or nil if there are none.
+func (s *Spider) landingMove() *move {
+	m := &move{from: s.Pose(), to: s.Pose(), easing: Linear}
+	height := 0.0
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if s.legs[leg].state != Swing {
+			continue
+		}
+		m.lands[leg] = true
+		m.to.Feet[leg].Z = s.walk.liftOff[leg].Z
+		height = math.Max(height, math.Abs(m.from.Feet[leg].Z-m.to.Feet[leg].Z))
+	}
+	if m.lands == ([4]bool{}) {
+		return nil
+	}
+	m.duration = time.Duration(height / landingSpeed * float64(time.Second))
+	return m
+}
+
+// legPhase returns the fraction of a cycle since the leg last touched down, at the given gait phase.
+func (w *walker) legPhase(phase float64, leg LegPosition) float64 {
+	p := phase - w.config.Pattern.Offsets[leg]
+	return p - math.Floor(p)
+}
+
+// neutralToePoint returns the toe point each foot returns to when standing still.
+func (s *Spider) neutralToePoint() Point3D {
+	return Point3D{Z: TibiaLength - s.walk.config.Gait.BodyHeight}
+}
+
+// footVelocity returns the velocity, relative to the body, of a planted foot at the given toe point.
+func (s *Spider) footVelocity(v velocity, leg LegPosition, pt Point3D) Point3D {
+	p := s.legs[leg].origin.Add(pt)
+	return Point3D{
+		X: -(v.x - v.omega*p.Y),
+		Y: -(v.y + v.omega*p.X),
+	}
+}
+
+// approach moves from towards to by no more than step.
+func approach(from, to, step float64) float64 {
+	if to > from+step {
+		return from + step
+	}
+	if to < from-step {
+		return from - step
+	}
+	return to
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spider
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSetVelocityAccelerationLimit(t *testing.T) {
+	s := newTestSpider()
+	s.SetVelocity(0, 8, 0.12)
+	s.Update(100 * time.Millisecond)
+	vx, vy, omega := s.Velocity()
+	if vx != 0 || math.Abs(vy-4) > 1e-9 || math.Abs(omega-0.1) > 1e-9 {
+		t.Errorf("Velocity() = (%v, %v, %v) after 100ms, want (0, 4, 0.1)", vx, vy, omega)
+	}
+	for i := 0; i < 100; i++ {
+		s.Update(10 * time.Millisecond)
+	}
+	vx, vy, omega = s.Velocity()
+	if vx != 0 || vy != 8 || omega != 0.12 {
+		t.Errorf("Velocity() = (%v, %v, %v) after 1s, want (0, 8, 0.12)", vx, vy, omega)
+	}
+}
+
+func TestSetVelocityLimitsStride(t *testing.T) {
+	s := newTestSpider()
+	s.SetVelocity(0, 1000, 0)
+	cfg := DefaultWalkConfig
+	stanceTime := cfg.Pattern.DutyFactor * cfg.Gait.Period.Seconds()
+	if got, want := s.walk.cmd.y, cfg.Gait.StrideLength/stanceTime; math.Abs(got-want) > 1e-9 {
+		t.Errorf("commanded velocity is %v, want %v", got, want)
+	}
+}
+
+// stanceFootVelocities walks for a while at the given velocity, then returns the velocity of each foot which stays in
+// stance for a tick, and its body frame position.
+func stanceFootVelocities(vx, vy, omega float64) (vels []Point3D, positions []Point3D) {
+	s := newTestSpider()
+	s.SetVelocity(vx, vy, omega)
+	const dt = 10 * time.Millisecond
+	for i := 0; i < 300; i++ {
+		s.Update(dt)
+	}
+	for i := 0; i < 200; i++ {
+		before := s.feet
+		stance := s.stance()
+		s.Update(dt)
+		for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+			if !stance[leg] || !s.stance()[leg] {
+				continue
+			}
+			vels = append(vels, s.feet[leg].Sub(before[leg]).Scale(1/dt.Seconds()))
+			positions = append(positions, before[leg].Add(s.legs[leg].origin))
+		}
+	}
+	return vels, positions
+}
+
+func TestWalkStanceFeetMoveOppositeBody(t *testing.T) {
+	vels, _ := stanceFootVelocities(10, 20, 0)
+	if len(vels) == 0 {
+		t.Fatal("no feet were in stance")
+	}
+	for _, v := range vels {
+		if math.Abs(v.X+10) > 1e-6 || math.Abs(v.Y+20) > 1e-6 || v.Z != 0 {
+			t.Errorf("stance foot velocity is %v, want (-10, -20, 0)", v)
+		}
+	}
+}
+
+func TestWalkTurningRotatesStrideAboutCentre(t *testing.T) {
+	const omega = 0.2
+	vels, positions := stanceFootVelocities(0, 0, omega)
+	if len(vels) == 0 {
+		t.Fatal("no feet were in stance")
+	}
+	for i, v := range vels {
+		p := positions[i]
+		// Turning left, each foot sweeps clockwise about the centre of the body.
+		want := Point3D{X: omega * p.Y, Y: -omega * p.X}
+		if !approxEqualWithin(v, want, 1e-6) {
+			t.Errorf("stance foot at %v has velocity %v, want %v", p, v, want)
+		}
+	}
+}
+
+func TestWalkStopReturnsToNeutral(t *testing.T) {
+	s := newTestSpider()
+	s.SetVelocity(15, 15, 0.2)
+	for i := 0; i < 300; i++ {
+		s.Update(10 * time.Millisecond)
+	}
+	s.SetVelocity(0, 0, 0)
+	for i := 0; i < 1000 && s.Walking(); i++ {
+		s.Update(10 * time.Millisecond)
+	}
+	if s.Walking() {
+		t.Fatal("Walking() is still true 10s after stopping")
+	}
+	for leg := LegPosition(0); leg < LegPosition(4); leg++ {
+		if got, want := s.ToePoint(leg), s.neutralToePoint(); !approxEqualWithin(got, want, neutralTolerance) {
+			t.Errorf("leg %v stopped at %v, want %v", leg, got, want)
+		}
+	}
+}